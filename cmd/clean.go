@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/aymaneallaoui/GoNext/pkg/builder"
+	"github.com/spf13/cobra"
+)
+
+var cleanOpts = builder.CleanOptions{OlderThan: time.Hour}
+
+// cleanCmd removes leftover temp directories and the frontend build cache
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove temp directories left by interrupted builds and clear the build cache",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		opts := cleanOpts
+		opts.Verbose, opts.Quiet = verbose, quiet
+		return builder.Clean(opts)
+	},
+}
+
+func init() {
+	cleanCmd.Flags().BoolVar(&cleanOpts.DryRun, "dry-run", false, "list what would be removed without removing anything")
+	cleanCmd.Flags().StringVar(&cleanOpts.CacheDir, "cache-dir", "", "build cache directory to clear, as passed to --cache-dir when building (default: gonext in the OS cache directory)")
+	cleanCmd.Flags().DurationVar(&cleanOpts.OlderThan, "older-than", cleanOpts.OlderThan, "only remove gonext-* temp directories last modified at least this long ago, so running builds keep theirs (0 removes all)")
+	RootCmd.AddCommand(cleanCmd)
+}
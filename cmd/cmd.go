@@ -1,350 +1,173 @@
 package cmd
 
 import (
-	_ "fmt"
-	"io/fs"
-	"log"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"runtime"
-	"text/template"
+	"fmt"
 
+	"github.com/aymaneallaoui/GoNext/pkg/builder"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
-// Template for the main.go file
-const mainTemplate = `package main
-
-import (
-	"bytes"
-	"context"
-	"embed"
-	"fmt"
-	"io"
-	"io/fs"
-	"log"
-	"net/http"
-	"os"
-	"os/exec"
-	"os/signal"
-	"runtime"
-	"strings"
-	"syscall"
-	"time"
-)
-
-//go:embed {{.EmbedPath}}
-var frontendFS embed.FS
-
-// Get the backend binary name based on the platform
-func getBackendBinaryName() string {
-	binary := "./backend-binary"
-	if runtime.GOOS == "windows" {
-		binary += ".exe"
-	}
-	return binary
-}
-
-// Start the backend process
-func startBackend() (*exec.Cmd, error) {
-	log.Println("Starting backend process...")
-	cmd := exec.Command(getBackendBinaryName())
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	err := cmd.Start()
-	if err != nil {
-		return nil, err
-	}
-	return cmd, nil
-}
-
-// File server that serves everything in the embedded folder
-func startServer() (*http.ServeMux, error) {
-	fsys, err := fs.Sub(frontendFS, "{{.FrontendDir}}")
-	if err != nil {
-		return nil, err
-	}
-
-	mux := http.NewServeMux()
-
-	// Serve all static files using http.FileServer
-	fileServer := http.FileServer(http.FS(fsys))
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		// Try to serve the requested file
-		if fileExists(fsys, r.URL.Path) {
-			fileServer.ServeHTTP(w, r)
-		} else {
-			// If the file doesn't exist, serve index.html for client-side routing
-			f, err := fsys.Open("index.html")
-			if err != nil {
-				http.Error(w, "index.html not found", http.StatusNotFound)
-				return
-			}
-			defer f.Close()
-
-			// Read the content of index.html
-			content, err := io.ReadAll(f)
-			if err != nil {
-				http.Error(w, "failed to read index.html", http.StatusInternalServerError)
-				return
-			}
-
-			// Serve index.html using bytes.Reader which implements io.ReadSeeker
-			http.ServeContent(w, r, "index.html", time.Now(), bytes.NewReader(content))
-		}
-	})
-
-	log.Println("Frontend server is set up to serve all files in the embedded folder.")
-
-	return mux, nil
-}
-
-// Check if a file exists in the embedded filesystem
-func fileExists(fsys fs.FS, filePath string) bool {
-	if filePath == "/" {
-		filePath = "index.html" // Serve index.html if root is requested
-	}
-
-	// Attempt to open the file
-	_, err := fsys.Open(strings.TrimPrefix(filePath, "/"))
-	return err == nil
-}
-
-// StartHTTPServer starts the HTTP server with graceful shutdown
-func startHTTPServer(server *http.Server) {
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
-
-	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("HTTP server failed: %v", err)
-		}
-	}()
+var (
+	// Build options bound to the root command's flags
+	buildOpts = builder.DefaultOptions()
 
-	log.Println("HTTP server is running on", server.Addr)
+	verbose    bool
+	quiet      bool
+	outputName string
 
-	<-stop
-
-	log.Println("Shutting down HTTP server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("HTTP server graceful shutdown failed: %v", err)
-	}
-	log.Println("HTTP server stopped.")
-}
-
-func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	// Start backend process
-	backendCmd, err := startBackend()
-	if err != nil {
-		log.Fatalf("Failed to start backend: %v", err)
-	}
-	defer func() {
-		// Ensure backend process is stopped when the application shuts down
-		if backendCmd != nil && backendCmd.Process != nil {
-			backendCmd.Process.Kill()
-		}
-	}()
-
-	// Setup frontend server
-	mux, err := startServer()
-	if err != nil {
-		log.Fatalf("Failed to start frontend server: %v", err)
-	}
-
-	// Create HTTP server with mux and address
-	server := &http.Server{
-		Addr:    fmt.Sprintf(":%s", port),
-		Handler: mux,
-	}
-
-	// Start HTTP server with graceful shutdown
-	startHTTPServer(server)
-}
-
-`
+	// Logs the CLI's own messages, set up from --verbose and --quiet
+	logger = builder.NewLogger(false, false)
+)
 
 // RootCmd defines the base command for Cobra
 var RootCmd = &cobra.Command{
 	Use:   "GoNext <backend> <frontend> <output-dir> <binary-name>",
 	Short: "GoNext CLI generates a Go web server from backend and frontend files",
-	Args:  cobra.ExactArgs(4),
-	Run:   run,
-}
-
-func run(cmd *cobra.Command, args []string) {
-	backendPath := args[0]
-	frontendPath := args[1]
-	outputDir := args[2]
-	outputBinary := filepath.Join(outputDir, args[3])
-
-	// Add correct file extension based on the platform
-	outputBinary = addPlatformExtension(outputBinary)
-
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	log.Printf("Backend path: %s", backendPath)
-	log.Printf("Frontend path: %s", frontendPath)
-	log.Printf("Output binary: %s", outputBinary)
-
-	tempDir, err := os.MkdirTemp("", "gonext-")
+	Long: `GoNext CLI generates a Go web server from backend and frontend files.
+
+Arguments and flags can also be set in a gonext.yaml (or gonext.json) file in
+the working directory, using the argument and flag names as keys. Values given
+on the command line take precedence over the file.
+
+With --no-backend the bundle only serves the frontend, and the backend argument
+is dropped: GoNext --no-backend <frontend> <output-dir> <binary-name>`,
+	Args:              cobra.MaximumNArgs(len(positionalKeys)),
+	ValidArgsFunction: completePositionalDirs,
+	RunE:              run,
+	// main reports the error, once
+	SilenceErrors: true,
+}
+
+func init() {
+	RootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "show detailed output including full commands and paths")
+	RootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "only show errors")
+	RootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
+	RootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		logger = builder.NewLogger(verbose, quiet)
+	}
+
+	addFrontendFlags(RootCmd.Flags())
+	addBackendFlags(RootCmd.Flags())
+	addBackendBuildFlags(RootCmd.Flags())
+	RootCmd.Flags().StringVar(&buildOpts.LDFlags, "ldflags", buildOpts.LDFlags, "-ldflags passed to go build for the final binary")
+	RootCmd.Flags().BoolVar(&buildOpts.TrimPath, "trimpath", buildOpts.TrimPath, "build the final binary and Go backends with -trimpath so they don't contain build machine paths")
+	RootCmd.Flags().BoolVar(&buildOpts.Strip, "strip", buildOpts.Strip, "strip the symbol table and DWARF debug info from the final binary (-ldflags \"-s -w\")")
+	RootCmd.Flags().StringVar(&buildOpts.Tags, "tags", buildOpts.Tags, "build tags (comma or space separated) for the final binary")
+	RootCmd.Flags().StringVar(&buildOpts.AppVersion, "app-version", buildOpts.AppVersion, "application version reported by the bundle's version command")
+	RootCmd.Flags().StringVar(&outputName, "output-name", "", "file name of the bundled binary, instead of the binary-name argument")
+	RootCmd.Flags().StringVar(&buildOpts.EmbedDir, "embed-dir", buildOpts.EmbedDir, "directory name the frontend is copied to and embedded under in the generated module (default: the frontend directory's base name)")
+	RootCmd.Flags().StringVar(&buildOpts.ModulePath, "module", buildOpts.ModulePath, "module path of the generated Go module")
+	RootCmd.Flags().StringVar(&buildOpts.GoVersion, "go-version", buildOpts.GoVersion, "go directive for the generated module (default: the installed toolchain's version)")
+	RootCmd.Flags().BoolVar(&buildOpts.Reproducible, "reproducible", buildOpts.Reproducible, "build byte-identical bundles from identical inputs: fixes the build date and file times to SOURCE_DATE_EPOCH (or the Unix epoch) and builds with -trimpath")
+	RootCmd.Flags().DurationVar(&buildOpts.Timeout, "timeout", buildOpts.Timeout, "fail the build if it takes longer than this, killing any running step (0 means no limit)")
+	RootCmd.Flags().StringVar(&buildOpts.GOOS, "goos", buildOpts.GOOS, "target operating system for the backend and final binary (defaults to the host)")
+	RootCmd.Flags().StringVar(&buildOpts.GOARCH, "goarch", buildOpts.GOARCH, "target architecture for the backend and final binary (defaults to the host)")
+	RootCmd.Flags().DurationVar(&buildOpts.BackendReadyTimeout, "backend-ready-timeout", buildOpts.BackendReadyTimeout, "how long the generated server waits for the backend to become ready (0 disables the check)")
+	RootCmd.Flags().StringVar(&buildOpts.BackendHealthPath, "backend-health-path", buildOpts.BackendHealthPath, "backend HTTP path polled for readiness and by the bundle's health endpoint (a TCP dial is used when empty)")
+	RootCmd.Flags().BoolVar(&buildOpts.NoRestart, "no-restart", buildOpts.NoRestart, "do not restart the backend when it exits unexpectedly")
+	RootCmd.Flags().DurationVar(&buildOpts.BackendGracePeriod, "backend-grace-period", buildOpts.BackendGracePeriod, "how long the backend may take to exit after SIGTERM before it is killed")
+	RootCmd.Flags().DurationVar(&buildOpts.ReadHeaderTimeout, "read-header-timeout", buildOpts.ReadHeaderTimeout, "how long the generated server waits for request headers (0 disables)")
+	RootCmd.Flags().DurationVar(&buildOpts.ReadTimeout, "read-timeout", buildOpts.ReadTimeout, "how long the generated server waits for a whole request, including the body (0 disables)")
+	RootCmd.Flags().DurationVar(&buildOpts.WriteTimeout, "write-timeout", buildOpts.WriteTimeout, "how long the generated server may take to write a response (0 disables, e.g. for long-lived streaming responses)")
+	RootCmd.Flags().DurationVar(&buildOpts.IdleTimeout, "idle-timeout", buildOpts.IdleTimeout, "how long the generated server keeps idle keep-alive connections open (0 uses --read-timeout)")
+	RootCmd.Flags().DurationVar(&buildOpts.ShutdownTimeout, "shutdown-timeout", buildOpts.ShutdownTimeout, "how long the generated server lets in-flight requests finish on shutdown, overridable with the SHUTDOWN_TIMEOUT environment variable")
+	RootCmd.Flags().BoolVar(&buildOpts.Compression, "compression", buildOpts.Compression, "gzip static responses and serve pre-compressed .br/.gz files in the generated server")
+	RootCmd.Flags().StringVar(&buildOpts.ImmutablePattern, "immutable-pattern", buildOpts.ImmutablePattern, "regular expression for fingerprinted asset paths served with a one-year immutable Cache-Control (empty disables)")
+	RootCmd.Flags().BoolVar(&buildOpts.CompressAssets, "compress-assets", buildOpts.CompressAssets, "gzip frontend assets at build time to shrink the final binary (implies --compression)")
+	RootCmd.Flags().BoolVar(&buildOpts.StripSourceMaps, "strip-sourcemaps", buildOpts.StripSourceMaps, "leave *.map source map files out of the embedded frontend")
+	RootCmd.Flags().BoolVar(&buildOpts.MinifyHTML, "minify-html", buildOpts.MinifyHTML, "minify .html files when copying the frontend into the bundle")
+	RootCmd.Flags().StringArrayVar(&buildOpts.Exclude, "exclude", buildOpts.Exclude, "glob pattern of frontend files or directories (with a trailing /) to leave out of the bundle, matched against the path relative to the build output, or the base name when it has no slash; repeatable")
+	RootCmd.Flags().StringVar(&buildOpts.TLSCert, "tls-cert", buildOpts.TLSCert, "TLS certificate file the generated server reads at runtime to serve HTTPS")
+	RootCmd.Flags().StringVar(&buildOpts.TLSKey, "tls-key", buildOpts.TLSKey, "TLS private key file the generated server reads at runtime to serve HTTPS")
+	RootCmd.Flags().StringVar(&buildOpts.Host, "host", buildOpts.Host, "default bind host for the generated server, overridable with the HOST environment variable (all interfaces when empty)")
+	RootCmd.Flags().StringSliceVar(&buildOpts.CORSOrigins, "cors-origins", buildOpts.CORSOrigins, "origins allowed to call the proxied API cross-origin, or * for any (CORS is disabled when empty)")
+	RootCmd.Flags().StringSliceVar(&buildOpts.CORSMethods, "cors-methods", buildOpts.CORSMethods, "methods allowed in CORS preflight responses")
+	RootCmd.Flags().StringSliceVar(&buildOpts.CORSHeaders, "cors-headers", buildOpts.CORSHeaders, "request headers allowed in CORS preflight responses")
+	RootCmd.Flags().BoolVar(&buildOpts.SPAFallback, "spa-fallback", buildOpts.SPAFallback, "serve index.html for missing paths so client-side routes work; set to false to return 404 (with 404.html if present) for static multi-page sites")
+	RootCmd.Flags().BoolVar(&buildOpts.StrictRoutes, "strict-routes", buildOpts.StrictRoutes, "only serve the SPA entry for missing paths when the request accepts text/html (a browser navigation); other requests get 404")
+	RootCmd.Flags().StringVar(&buildOpts.SPAEntry, "spa-entry", buildOpts.SPAEntry, "file in the frontend output served for client-side routes (e.g. 200.html or app.html)")
+	RootCmd.Flags().BoolVar(&buildOpts.TrailingSlash, "trailing-slash", buildOpts.TrailingSlash, "match Next.js trailingSlash: true, redirecting /about to /about/ (by default /about/ redirects to /about); both serve about/index.html")
+	RootCmd.Flags().BoolVar(&buildOpts.AccessLog, "access-log", buildOpts.AccessLog, "log method, path, status and latency of every request served by the bundle")
+	RootCmd.Flags().StringVar(&buildOpts.BasePath, "base-path", buildOpts.BasePath, "path prefix (e.g. /dashboard) the bundle is served under behind a reverse proxy; also passed to the frontend build as NEXT_PUBLIC_BASE_PATH")
+	RootCmd.Flags().BoolVar(&buildOpts.Dotenv, "dotenv", buildOpts.Dotenv, "have the bundle load a .env file next to it (or at $DOTENV_PATH) at startup, without overriding variables already set")
+	RootCmd.Flags().BoolVar(&buildOpts.Manifest, "manifest", buildOpts.Manifest, "serve a JSON manifest describing the bundle (version, build time, routes, backends) at --manifest-path")
+	RootCmd.Flags().StringVar(&buildOpts.ManifestPath, "manifest-path", buildOpts.ManifestPath, "path of the generated server's manifest endpoint")
+	RootCmd.Flags().BoolVar(&buildOpts.Metrics, "metrics", buildOpts.Metrics, "expose Prometheus request and backend metrics at /metrics on the generated server")
+	RootCmd.Flags().DurationVar(&buildOpts.HealthzCacheTTL, "healthz-cache-ttl", buildOpts.HealthzCacheTTL, "how long the health endpoint reuses its backend check result (0 checks on every request)")
+	RootCmd.Flags().StringVar(&buildOpts.HealthzPath, "healthz-path", buildOpts.HealthzPath, "path of the generated server's health endpoint, which also checks the backend (empty to disable)")
+	RootCmd.Flags().StringVar(&buildOpts.BackendBinary, "backend-binary", buildOpts.BackendBinary, "existing backend executable to bundle instead of building one with go build (drops the backend argument)")
+	RootCmd.Flags().StringVar(&buildOpts.BackendCommand, "backend-cmd", buildOpts.BackendCommand, "command the bundle runs as its backend instead of a Go binary, e.g. \"uvicorn app:main --port 5000\"; it must be available where the bundle runs (implies --skip-backend-build)")
+	RootCmd.Flags().BoolVar(&buildOpts.SkipBackendBuild, "skip-backend-build", buildOpts.SkipBackendBuild, "don't build or bundle a backend, running --backend-cmd instead (drops the backend argument)")
+	RootCmd.Flags().StringVar(&buildOpts.BackendBinaryName, "backend-binary-name", buildOpts.BackendBinaryName, "file name of the backend binary inside or next to the bundle")
+	RootCmd.Flags().StringSliceVar(&buildOpts.Backends, "backends", buildOpts.Backends, "build each listed directory under the backend path as its own backend process, as name or name:port (ports default to --backend-port upwards; the first receives API requests)")
+	RootCmd.Flags().IntVar(&buildOpts.BuildRetries, "build-retries", buildOpts.BuildRetries, "retry a failed frontend build up to this many times, with exponential backoff")
+	RootCmd.Flags().BoolVar(&buildOpts.PrebuiltFrontend, "prebuilt-frontend", buildOpts.PrebuiltFrontend, "skip the frontend build and bundle its existing output directory (see --out-dir)")
+	RootCmd.Flags().StringVar(&buildOpts.CacheDir, "cache-dir", buildOpts.CacheDir, "directory frontend build outputs are cached in, keyed by a hash of the frontend source (default: gonext in the OS cache directory)")
+	RootCmd.Flags().BoolVar(&buildOpts.NoCache, "no-cache", buildOpts.NoCache, "always rebuild the frontend, without reading or writing the build cache")
+	RootCmd.Flags().StringArrayVar(&buildOpts.Frontends, "frontend", buildOpts.Frontends, "embed a frontend app as name=path[:prefix], served under /prefix or the site root; repeat for several apps, each with its own SPA fallback (replaces the frontend argument)")
+	RootCmd.Flags().BoolVar(&buildOpts.NoBackend, "no-backend", buildOpts.NoBackend, "build a static-only bundle that serves the frontend without a backend (drops the backend argument)")
+	RootCmd.Flags().BoolVar(&buildOpts.ExternalBackend, "external-backend", buildOpts.ExternalBackend, "ship the backend binary alongside the bundle instead of embedding it")
+	RootCmd.Flags().StringVar(&buildOpts.TemplatePath, "template", buildOpts.TemplatePath, "Go text/template file used to generate main.go instead of the built-in one; it receives the same fields (.EmbedPath, .FrontendDir, .Backends, ...)")
+	RootCmd.Flags().BoolVar(&buildOpts.KeepTemp, "keep-temp", buildOpts.KeepTemp, "keep the temp build directory (generated main.go, go.mod, copied assets) and log its path on exit")
+	RootCmd.Flags().BoolVar(&buildOpts.Systemd, "systemd", buildOpts.Systemd, "write a <binary-name>.service systemd unit that runs the bundle into the output directory")
+	RootCmd.Flags().BoolVar(&buildOpts.Docker, "docker", buildOpts.Docker, "write a Dockerfile for the bundle into the output directory (the bundle is built with CGO disabled and listens on all interfaces; not supported with --backend-cmd)")
+	RootCmd.Flags().StringVar(&buildOpts.DockerTag, "docker-tag", buildOpts.DockerTag, "build a Docker image with this tag from the generated Dockerfile (implies --docker)")
+	RootCmd.Flags().StringVar(&buildOpts.DockerBase, "docker-base", buildOpts.DockerBase, "base image of the generated Dockerfile")
+	RootCmd.Flags().StringArrayVar(&buildOpts.PreBuildCommands, "pre-build-cmd", buildOpts.PreBuildCommands, "command run before the frontend and backend builds, e.g. a code generator; a failure fails the build. Hooks get GONEXT_BACKEND_PATH, GONEXT_FRONTEND_PATH, GONEXT_OUTPUT_DIR, GONEXT_OUTPUT_BINARY and other GONEXT_* variables; repeatable")
+	RootCmd.Flags().StringArrayVar(&buildOpts.PostBuildCommands, "post-build-cmd", buildOpts.PostBuildCommands, "command run after the bundle and its outputs are written, e.g. an upload, with the same GONEXT_* variables as --pre-build-cmd; repeatable")
+	RootCmd.Flags().BoolVar(&buildOpts.DryRun, "dry-run", buildOpts.DryRun, "log the build plan with resolved commands and paths without running or writing anything")
+	registerFlagCompletions(RootCmd)
+}
+
+// Registers the flags that control how the frontend is built
+func addFrontendFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&buildOpts.Framework, "frontend-framework", buildOpts.Framework, "frontend framework used to build the static site (nextjs, vite, cra, sveltekit); detected from package.json and config files when empty")
+	flags.StringVar(&buildOpts.PackageManager, "package-manager", buildOpts.PackageManager, "package manager used to build the frontend (npm, pnpm, yarn); detected from lockfiles when empty")
+	flags.StringVar(&buildOpts.OutDir, "out-dir", buildOpts.OutDir, "frontend build output directory relative to the frontend path (defaults to the framework's output directory)")
+	flags.BoolVar(&buildOpts.NoInstall, "no-install", buildOpts.NoInstall, "don't install frontend dependencies when node_modules is missing")
+	flags.StringVar(&buildOpts.InstallCommand, "frontend-install-cmd", buildOpts.InstallCommand, "command that installs frontend dependencies instead of the package manager's install, e.g. \"pnpm install --filter web...\" in a monorepo")
+	flags.StringVar(&buildOpts.InstallDir, "frontend-install-dir", buildOpts.InstallDir, "directory the install runs in, whose package.json, lockfile and node_modules decide whether and how to install, e.g. the monorepo root (defaults to the frontend path)")
+	flags.StringArrayVar(&buildOpts.FrontendEnv, "env", buildOpts.FrontendEnv, "KEY=VALUE environment variable for the frontend build, e.g. NEXT_PUBLIC_API_URL; repeatable")
+	flags.StringVar(&buildOpts.BuildScript, "build-script", buildOpts.BuildScript, "package.json script that builds the frontend, instead of the framework's (build)")
+	flags.StringVar(&buildOpts.BuildArgs, "build-args", buildOpts.BuildArgs, "extra arguments appended to the frontend build script, split like a shell command line, e.g. \"--debug --config prod.config.js\"")
+	flags.StringVar(&buildOpts.FrontendEnvFile, "env-file", buildOpts.FrontendEnvFile, ".env file of variables for the frontend build (--env values take precedence)")
+}
+
+// Registers the flags passed through to go build for the backend
+func addBackendBuildFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&buildOpts.BackendLDFlags, "backend-ldflags", buildOpts.BackendLDFlags, "-ldflags passed to go build for the backend, e.g. \"-X main.version=1.2.3\"")
+	flags.StringVar(&buildOpts.BackendTags, "backend-tags", buildOpts.BackendTags, "build tags (comma or space separated) for the backend")
+}
+
+// Registers the flags that control how the backend is reached
+func addBackendFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&buildOpts.APIPrefix, "api-prefix", buildOpts.APIPrefix, "path prefix proxied to the backend by the generated server (empty disables the proxy)")
+	flags.IntVar(&buildOpts.BackendPort, "backend-port", buildOpts.BackendPort, "default port for the backend, passed to it in the BACKEND_PORT environment variable")
+}
+
+// Parses the flags, positional arguments and config file into build options
+// and runs the build
+func run(cmd *cobra.Command, args []string) error {
+	args, err := applyConfig(cmd, args)
 	if err != nil {
-		log.Fatalf("Failed to create temp directory: %v", err)
-	}
-	defer os.RemoveAll(tempDir)
-	log.Printf("Created temp directory: %s", tempDir)
-
-	// Build the Next.js frontend
-	if err := buildNextJS(frontendPath); err != nil {
-		log.Fatalf("Failed to build frontend: %v", err)
-	}
-	log.Println("Next.js frontend built successfully")
-
-	// Copy only the built frontend (frontend/out)
-	fullFrontendPath := filepath.Join(frontendPath, "out")
-	destFrontendPath := filepath.Join(tempDir, filepath.Base(frontendPath))
-	if err := copyDir(fullFrontendPath, destFrontendPath); err != nil {
-		log.Fatalf("Failed to copy built frontend files: %v", err)
-	}
-	log.Println("Frontend files copied successfully")
-
-	// Build the Go backend
-	builtBackendBinary := filepath.Join(tempDir, "backend-binary")
-	builtBackendBinary = addPlatformExtension(builtBackendBinary)
-	if err := buildGoBackend(backendPath, builtBackendBinary); err != nil {
-		log.Fatalf("Failed to build backend: %v", err)
-	}
-	log.Println("Go backend built successfully")
-
-	// Copy the Go backend binary to the output directory
-	if err := copyFile(builtBackendBinary, outputBinary); err != nil {
-		log.Fatalf("Failed to copy backend binary to output: %v", err)
-	}
-	log.Printf("Backend binary copied to: %s", outputBinary)
-
-	// Generate main.go
-	mainFile := filepath.Join(tempDir, "main.go")
-	if err := generateMain(mainFile, filepath.Base(frontendPath)); err != nil {
-		log.Fatalf("Failed to generate main.go: %v", err)
-	}
-	log.Println("main.go generated successfully")
-
-	// Initialize Go module
-	if err := initGoModule(tempDir); err != nil {
-		log.Fatalf("Failed to initialize Go module: %v", err)
-	}
-
-	// Build the final binary
-	if err := buildBinary(tempDir, outputBinary); err != nil {
-		log.Fatalf("Failed to build: %v", err)
-	}
-	log.Printf("Successfully created bundled binary: %s", outputBinary)
-}
-
-// Adds the correct file extension based on the platform
-func addPlatformExtension(binary string) string {
-	if runtime.GOOS == "windows" {
-		return binary + ".exe"
-	}
-	return binary
-}
-
-func buildNextJS(frontendPath string) error {
-	log.Println("Building Next.js frontend...")
-	cmd := exec.Command("npm", "run", "build")
-	cmd.Dir = frontendPath
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
-
-func buildGoBackend(backendPath, outputBinary string) error {
-	log.Println("Building Go backend...")
-	cmd := exec.Command("go", "build", "-o", outputBinary)
-	cmd.Dir = backendPath
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
-
-func copyDir(src, dst string) error {
-	return filepath.Walk(src, func(path string, info fs.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		relPath, err := filepath.Rel(src, path)
-		if err != nil {
-			return err
-		}
-
-		dstPath := filepath.Join(dst, relPath)
-
-		if info.IsDir() {
-			return os.MkdirAll(dstPath, info.Mode())
-		}
-
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
-
-		return os.WriteFile(dstPath, data, info.Mode())
-	})
-}
-
-func copyFile(src, dst string) error {
-	data, err := os.ReadFile(src)
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(dst, data, 0755)
-}
-
-func generateMain(filename, frontendDir string) error {
-	tmpl, err := template.New("main").Parse(mainTemplate)
-	if err != nil {
-		return err
-	}
-
-	file, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	data := struct {
-		EmbedPath   string
-		FrontendDir string
-	}{
-		EmbedPath:   frontendDir,
-		FrontendDir: frontendDir,
-	}
-
-	return tmpl.Execute(file, data)
-}
-
-func initGoModule(dir string) error {
-	log.Println("Initializing Go module...")
-	cmd := exec.Command("go", "mod", "init", "gonext")
-	cmd.Dir = dir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
-
-func buildBinary(tempDir, outputBinary string) error {
-	log.Println("Building the final binary...")
-	cmd := exec.Command("go", "build", "-o", outputBinary)
-	cmd.Dir = tempDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	// The config file may set --verbose or --quiet
+	logger = builder.NewLogger(verbose, quiet)
+	// Failures from here on aren't usage mistakes
+	cmd.SilenceUsage = true
+
+	opts := buildOpts
+	opts.BackendPath = args[0]
+	opts.FrontendPath = args[1]
+	opts.OutputDir = args[2]
+	opts.BinaryName = args[3]
+	opts.Verbose = verbose
+	opts.Quiet = quiet
+	opts.GoNextVersion = cliVersion()
+	if err := builder.Build(cmd.Context(), opts); err != nil {
+		return fmt.Errorf("build failed: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test that configuration, build and subcommand failures are returned from
+// Execute, so main exits with a non-zero code
+func TestRootCmdErrors(t *testing.T) {
+	dir := t.TempDir()
+	// init refuses to scaffold into a non-empty directory
+	if err := os.WriteFile(filepath.Join(dir, "existing"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"backend"}, want: "invalid configuration"},
+		{args: []string{filepath.Join(dir, "backend"), filepath.Join(dir, "missing"), dir, "app"}, want: "build failed"},
+		{args: []string{"inspect", filepath.Join(dir, "missing")}, want: "failed to inspect"},
+		{args: []string{"init", dir}, want: "failed to scaffold project"},
+		{args: []string{"init", filepath.Join(dir, "My App")}, want: "invalid project name"},
+	}
+
+	RootCmd.SetOut(io.Discard)
+	defer RootCmd.SetOut(nil)
+	for _, tt := range tests {
+		RootCmd.SetArgs(tt.args)
+		err := RootCmd.Execute()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Execute(%q): expected an error containing %q, but got %v", tt.args, tt.want, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "My App")); err == nil {
+		t.Errorf("Expected init to write nothing for an invalid project name")
+	}
+}
+
+// Test that init only accepts names valid in both go.mod and package.json
+func TestCheckProjectName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{name: "my-app", valid: true},
+		{name: "app.v2", valid: true},
+		{name: "My-App"},
+		{name: "my app"},
+		{name: "_app"},
+		{name: ".app"},
+		{name: "app@1"},
+		{name: "node_modules"},
+		{name: strings.Repeat("a", 215)},
+	}
+
+	for _, tt := range tests {
+		err := checkProjectName(tt.name)
+		if tt.valid && err != nil {
+			t.Errorf("checkProjectName(%q): expected no error, but got %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("checkProjectName(%q): expected an error, but got none", tt.name)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/aymaneallaoui/GoNext/pkg/builder"
+	"github.com/spf13/cobra"
+)
+
+// completionCmd writes a shell completion script to stdout
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate a shell completion script",
+	Long: `Generate a shell completion script for GoNext.
+
+To load completions in the current shell:
+
+  bash:       source <(GoNext completion bash)
+  zsh:        source <(GoNext completion zsh)
+  fish:       GoNext completion fish | source
+  powershell: GoNext completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+func init() {
+	RootCmd.AddCommand(completionCmd)
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	var err error
+	switch args[0] {
+	case "bash":
+		err = RootCmd.GenBashCompletionV2(os.Stdout, true)
+	case "zsh":
+		err = RootCmd.GenZshCompletion(os.Stdout)
+	case "fish":
+		err = RootCmd.GenFishCompletion(os.Stdout, true)
+	case "powershell":
+		err = RootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate %s completion: %w", args[0], err)
+	}
+	return nil
+}
+
+// Registers value suggestions for the flags of cmd that have a fixed or
+// discoverable set of values. Call after the flags are defined.
+func registerFlagCompletions(cmd *cobra.Command) {
+	fixed := map[string][]string{
+		"frontend-framework": builder.Frameworks(),
+		"package-manager":    {"npm", "pnpm", "yarn"},
+	}
+	for name, values := range fixed {
+		if cmd.Flags().Lookup(name) != nil {
+			cmd.RegisterFlagCompletionFunc(name, cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp))
+		}
+	}
+
+	for name, part := range map[string]int{"goos": 0, "goarch": 1} {
+		if cmd.Flags().Lookup(name) == nil {
+			continue
+		}
+		cmd.RegisterFlagCompletionFunc(name, func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+			return goPlatformValues(part), cobra.ShellCompDirectiveNoFileComp
+		})
+	}
+
+	for _, name := range []string{"tls-cert", "tls-key"} {
+		if cmd.Flags().Lookup(name) != nil {
+			cmd.MarkFlagFilename(name, "pem", "crt", "key")
+		}
+	}
+	if cmd.Flags().Lookup("frontend-install-dir") != nil {
+		cmd.MarkFlagDirname("frontend-install-dir")
+	}
+}
+
+// Completes the backend, frontend and output-dir arguments with directories
+func completePositionalDirs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) < len(positionalKeys)-1 {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// Returns the GOOS (part 0) or GOARCH (part 1) values the installed
+// toolchain supports
+func goPlatformValues(part int) []string {
+	out, err := exec.Command("go", "tool", "dist", "list").Output()
+	if err != nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	var values []string
+	for _, platform := range strings.Fields(string(out)) {
+		fields := strings.Split(platform, "/")
+		if len(fields) != 2 || seen[fields[part]] {
+			continue
+		}
+		seen[fields[part]] = true
+		values = append(values, fields[part])
+	}
+	sort.Strings(values)
+	return values
+}
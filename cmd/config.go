@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// Config files looked up in the working directory, in order of preference
+var configFileNames = []string{"gonext.yaml", "gonext.yml", "gonext.json"}
+
+// Positional arguments that may also be set in the config file, in order
+var positionalKeys = []string{"backend", "frontend", "output-dir", "binary-name"}
+
+// Finds the config file in dir, returning "" when there is none
+func findConfigFile(dir string) string {
+	for _, name := range configFileNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Applies the config file in the working directory to every flag that wasn't
+// set on the command line, and returns the positional arguments with missing
+// ones filled in from the file. Other config keys are flag names.
+func applyConfig(cmd *cobra.Command, args []string) ([]string, error) {
+	values := map[string]any{}
+	path := findConfigFile(".")
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// YAML is a superset of JSON, so this handles gonext.json too
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		logger.Infof("Using config file: %s", path)
+	}
+
+	configured := map[string]string{}
+	for _, key := range positionalKeys {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		// A list sets the flag of the same name instead, e.g. frontend mounts
+		if _, isList := value.([]any); isList && cmd.Flags().Lookup(key) != nil {
+			continue
+		}
+		configured[key] = fmt.Sprint(value)
+		delete(values, key)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		flag := cmd.Flags().Lookup(key)
+		if flag == nil {
+			return nil, fmt.Errorf("%s: unknown setting %q", path, key)
+		}
+		if flag.Changed {
+			continue
+		}
+		if err := setFlagFromConfig(flag, values[key]); err != nil {
+			return nil, fmt.Errorf("%s: invalid value for %q: %w", path, key, err)
+		}
+	}
+
+	// Arguments fill the positional slots in order, skipping the backend with
+	// --no-backend, --backend-binary or --backend-cmd and the frontend when
+	// --frontend mounts are given
+	skip := map[string]bool{
+		"backend":  buildOpts.NoBackend || buildOpts.BackendBinary != "" || buildOpts.BackendCommand != "",
+		"frontend": len(buildOpts.Frontends) > 0,
+	}
+	var slots []int
+	for i, key := range positionalKeys {
+		if !skip[key] {
+			slots = append(slots, i)
+		}
+	}
+	if len(args) > len(slots) {
+		return nil, fmt.Errorf("expected at most %d arguments with these flags, got %d", len(slots), len(args))
+	}
+
+	positional := make([]string, len(positionalKeys))
+	for i, arg := range args {
+		positional[slots[i]] = arg
+	}
+	if outputName != "" {
+		// --output-name stands in for the binary-name argument
+		if len(args) == len(slots) {
+			return nil, fmt.Errorf("--output-name can't be combined with the binary-name argument")
+		}
+		positional[len(positional)-1] = outputName
+	}
+
+	var missing []string
+	for _, i := range slots {
+		key := positionalKeys[i]
+		if positional[i] == "" {
+			positional[i] = configured[key]
+		}
+		if positional[i] == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required %s (pass as arguments or set in %s)", strings.Join(missing, ", "), configFileNames[0])
+	}
+	return positional, nil
+}
+
+// Sets a flag from a config value, calling Set once per item for lists
+func setFlagFromConfig(flag *pflag.Flag, value any) error {
+	if items, ok := value.([]any); ok {
+		for _, item := range items {
+			if err := flag.Value.Set(fmt.Sprint(item)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return flag.Value.Set(fmt.Sprint(value))
+}
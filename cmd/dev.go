@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aymaneallaoui/GoNext/pkg/builder"
+	"github.com/spf13/cobra"
+)
+
+var (
+	devPort int
+	devOpen bool
+)
+
+// devCmd runs the backend and serves the built frontend from disk, rebuilding on changes
+var devCmd = &cobra.Command{
+	Use:   "dev <backend> <frontend>",
+	Short: "Run the backend and frontend locally, rebuilding and restarting on file changes",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runDev,
+}
+
+func init() {
+	addFrontendFlags(devCmd.Flags())
+	addBackendFlags(devCmd.Flags())
+	addBackendBuildFlags(devCmd.Flags())
+	devCmd.Flags().IntVar(&devPort, "port", 8080, "port for the development server")
+	devCmd.Flags().BoolVar(&devOpen, "open", false, "open the development server in the default browser once it is listening")
+	devCmd.ValidArgsFunction = completePositionalDirs
+	registerFlagCompletions(devCmd)
+	RootCmd.AddCommand(devCmd)
+}
+
+func runDev(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	opts := builder.DevOptions{BuildOptions: buildOpts, Port: devPort, Open: devOpen}
+	opts.BackendPath, opts.FrontendPath = args[0], args[1]
+	opts.Verbose, opts.Quiet = verbose, quiet
+	opts.GoNextVersion = cliVersion()
+	if err := builder.Dev(cmd.Context(), opts); err != nil {
+		return fmt.Errorf("development server failed: %w", err)
+	}
+	return nil
+}
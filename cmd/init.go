@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/module"
+)
+
+// Files written by `GoNext init`, keyed by path relative to the project directory
+var scaffoldFiles = map[string]string{
+	"gonext.yaml": `# GoNext build configuration
+backend: back-end
+frontend: front-end
+output-dir: dist
+binary-name: {{.Name}}
+frontend-framework: nextjs
+`,
+
+	".gitignore": `/dist/
+`,
+
+	"back-end/go.mod": `module {{.Name}}/back-end
+
+go 1.22
+`,
+
+	"back-end/main.go": `package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+func helloHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintf(w, "Hello, World!")
+}
+
+func main() {
+	http.HandleFunc("/api/hello", helloHandler)
+
+	// GoNext passes the backend port in BACKEND_PORT
+	port := os.Getenv("BACKEND_PORT")
+	if port == "" {
+		port = "5000"
+	}
+
+	fmt.Printf("Server starting on port %s...\n", port)
+	if err := http.ListenAndServe(":"+port, nil); err != nil {
+		fmt.Println("Error starting server: ", err)
+	}
+}
+`,
+
+	"front-end/package.json": `{
+  "name": "front-end",
+  "version": "0.1.0",
+  "private": true,
+  "scripts": {
+    "dev": "next dev",
+    "build": "next build"
+  },
+  "dependencies": {
+    "next": "14.2.9",
+    "react": "^18",
+    "react-dom": "^18"
+  }
+}
+`,
+
+	"front-end/next.config.mjs": `/** @type {import('next').NextConfig} */
+const nextConfig = {
+  output: "export",
+};
+
+export default nextConfig;
+`,
+
+	"front-end/.gitignore": `/node_modules
+/.next/
+/out/
+`,
+
+	"front-end/app/layout.jsx": `export const metadata = {
+  title: "{{.Name}}",
+};
+
+export default function RootLayout({ children }) {
+  return (
+    <html lang="en">
+      <body>{children}</body>
+    </html>
+  );
+}
+`,
+
+	"front-end/app/page.jsx": `"use client";
+
+import { useEffect, useState } from "react";
+
+export default function Home() {
+  const [message, setMessage] = useState("Loading...");
+
+  useEffect(() => {
+    fetch("/api/hello")
+      .then((res) => res.text())
+      .then(setMessage)
+      .catch(() => setMessage("Backend unavailable"));
+  }, []);
+
+  return (
+    <main>
+      <h1>{{.Name}}</h1>
+      <p>{message}</p>
+    </main>
+  );
+}
+`,
+}
+
+// initCmd scaffolds a starter project with a Go backend and a Next.js frontend
+var initCmd = &cobra.Command{
+	Use:   "init <project-name>",
+	Short: "Scaffold a starter project with a Go backend and a Next.js frontend",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runInit,
+}
+
+func init() {
+	RootCmd.AddCommand(initCmd)
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	projectDir := args[0]
+	name := filepath.Base(projectDir)
+	if err := checkProjectName(name); err != nil {
+		return fmt.Errorf("invalid project name %q: %w", name, err)
+	}
+
+	if err := scaffoldProject(projectDir, name); err != nil {
+		return fmt.Errorf("failed to scaffold project: %w", err)
+	}
+	logger.Infof("Project %s created in %s", name, projectDir)
+	logger.Infof("Install the frontend dependencies with: cd %s && npm install", filepath.Join(projectDir, "front-end"))
+	return nil
+}
+
+// Verifies the project name works in the scaffold's go.mod module path and
+// follows the npm package name rules, as it also names the frontend app
+func checkProjectName(name string) error {
+	if err := module.CheckImportPath(name + "/back-end"); err != nil {
+		return fmt.Errorf("not valid in a Go module path: %w", err)
+	}
+	switch {
+	case len(name) > 214:
+		return errors.New("longer than 214 characters")
+	case strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_"):
+		return errors.New("must not start with . or _")
+	case name != strings.ToLower(name):
+		return errors.New("must be lowercase")
+	case strings.Trim(name, "abcdefghijklmnopqrstuvwxyz0123456789-._~") != "":
+		return errors.New("may only contain lowercase letters, digits, -, ., _ and ~")
+	case name == "node_modules" || name == "favicon.ico":
+		return errors.New("reserved by npm")
+	}
+	return nil
+}
+
+// Writes the scaffold files into dir, refusing to touch a non-empty directory
+func scaffoldProject(dir, name string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("directory %s is not empty", dir)
+	}
+
+	// Write files in a stable order so errors are reproducible
+	paths := make([]string, 0, len(scaffoldFiles))
+	for path := range scaffoldFiles {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	data := struct{ Name string }{Name: name}
+	for _, path := range paths {
+		tmpl, err := template.New(path).Parse(scaffoldFiles[path])
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		file, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		if err := tmpl.Execute(file, data); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
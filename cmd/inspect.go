@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aymaneallaoui/GoNext/pkg/builder"
+	"github.com/spf13/cobra"
+)
+
+var inspectJSON bool
+
+// inspectCmd prints the build information embedded in a bundle
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <binary>",
+	Short: "Show the embedded files, build metadata and routes of a bundled binary",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runInspect,
+}
+
+func init() {
+	inspectCmd.Flags().BoolVar(&inspectJSON, "json", false, "print the bundle information as JSON")
+	RootCmd.AddCommand(inspectCmd)
+}
+
+func runInspect(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	info, err := builder.ReadBundleInfo(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", args[0], err)
+	}
+	if inspectJSON {
+		out, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode bundle information: %w", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	printBundleInfo(info)
+	return nil
+}
+
+// Prints info as a human-readable summary
+func printBundleInfo(info builder.BundleInfo) {
+	fmt.Printf("Version:    %s\n", info.Version)
+	fmt.Printf("Commit:     %s\n", info.Commit)
+	fmt.Printf("Built:      %s\n", info.BuildDate)
+	fmt.Printf("GoNext:     %s\n", info.GoNext)
+	if len(info.Backends) > 0 {
+		fmt.Printf("Backends:   %s\n", strings.Join(info.Backends, ", "))
+	} else {
+		fmt.Printf("Backends:   none\n")
+	}
+
+	fmt.Println("Routes:")
+	routes := []struct{ name, value string }{
+		{"base path", info.Routes.BasePath},
+		{"api prefix", info.Routes.APIPrefix},
+		{"health", info.Routes.Health},
+		{"metrics", info.Routes.Metrics},
+		{"manifest", info.Routes.Manifest},
+		{"frontends", strings.Join(info.Routes.Frontends, ", ")},
+	}
+	for _, route := range routes {
+		if route.value != "" {
+			fmt.Printf("  %-11s %s\n", route.name+":", route.value)
+		}
+	}
+
+	var total int64
+	for _, file := range info.Files {
+		total += file.Size
+	}
+	fmt.Printf("Files (%d, %s):\n", len(info.Files), builder.FormatBytes(total))
+	for _, file := range info.Files {
+		fmt.Printf("  %10s  %s\n", builder.FormatBytes(file.Size), file.Path)
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// GoNext version, set at release build time with
+// -ldflags "-X github.com/aymaneallaoui/GoNext/cmd.version=<version>"
+var version = "dev"
+
+// versionCmd prints the GoNext version and the platform it was built for
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the GoNext version, Go version and platform",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("GoNext %s\n", cliVersion())
+		fmt.Printf("Go: %s\n", runtime.Version())
+		fmt.Printf("OS/Arch: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(versionCmd)
+}
+
+// Returns the version set at build time, falling back to the module version
+// recorded by go install
+func cliVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
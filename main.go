@@ -1,15 +1,22 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/aymaneallaoui/GoNext/cmd"
 )
 
 func main() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	if err := cmd.RootCmd.Execute(); err != nil {
+	// Ctrl-C cancels the running build, which removes its temp files and
+	// returns an error, so the exit code is decided here
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := cmd.RootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
 		log.Fatalf("Error executing the command: %v", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,3168 @@
+package builder
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Template for the main.go file
+const mainTemplate = `package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"embed"{{if .ManifestPath}}
+	"encoding/json"{{end}}
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net"
+	"net/http"{{if .Backend}}
+	"net/http/httputil"{{end}}
+	"net/url"
+	"os"{{if .Backend}}
+	"os/exec"{{end}}
+	"os/signal"
+	"path"{{if or .Backend .Dotenv}}
+	"path/filepath"{{end}}
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"{{if and .Backend (eq .GOOS "windows")}}
+	"unsafe"{{end}}{{if .Metrics}}
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"{{end}}
+)
+
+// all: includes names starting with . or _, such as Next.js's _next directory
+//go:embed{{range .Frontends}} {{printf "all:%s" .Dir | printf "%q"}}{{end}}
+var frontendFS embed.FS
+
+// Embedded frontend directories, the path prefixes they are served at, where
+// "" is the site root, and the framework's asset directory inside them
+var frontends = []struct{ dir, prefix, assets string }{
+{{- range .Frontends}}
+	{dir: {{printf "%q" .Dir}}, prefix: {{printf "%q" .Prefix}}, assets: {{printf "%q" .AssetDir}}},
+{{- end}}
+}
+
+// Time the bundle was generated, used as the mod-time of embedded files
+var buildTime = time.Unix({{.BuildTime.Unix}}, 0).UTC()
+
+{{if .Backend}}
+// Requests under this path prefix are proxied to the backend process
+const apiPrefix = {{printf "%q" .APIPrefix}}
+
+// A backend process launched and supervised by the bundle
+type backendProcess struct {
+	name    string   // binary file name
+	port    string   // default value of the process's BACKEND_PORT
+	command []string // command run instead of a bundled binary, if set
+}
+
+// Backend processes in start order. API requests are proxied to the first.
+var backends = []backendProcess{
+{{- range .Backends}}
+	{name: {{printf "%q" .Binary}}, port: "{{.Port}}"{{if .Command}}, command: []string{ {{- range $i, $arg := .Command}}{{if $i}}, {{end}}{{printf "%q" $arg}}{{end -}} }{{end}}},
+{{- end}}
+}
+
+// Get the port of the proxied backend from BACKEND_PORT, falling back to
+// the default. Each backend process receives its resolved port in its
+// BACKEND_PORT environment variable and should listen on it.
+func getBackendPort() string {
+	if port := os.Getenv("BACKEND_PORT"); port != "" {
+		return port
+	}
+	return backends[0].port
+}
+
+{{if .EmbedBackend}}
+//go:embed{{range .Backends}} {{printf "backends/%s" .Binary | printf "%q"}}{{end}}
+var backendFS embed.FS
+
+// Directory the embedded backend binaries are extracted into
+var (
+	extractOnce  sync.Once
+	extractedDir string
+	extractErr   error
+)
+
+// Extract the embedded backend binaries into a temp directory
+func extractBackends() error {
+	dir, err := os.MkdirTemp("", "gonext-backend-")
+	if err != nil {
+		return err
+	}
+	extractedDir = dir
+	for _, b := range backends {
+		data, err := backendFS.ReadFile("backends/" + b.name)
+		if err != nil {
+			return err
+		}
+		binary := filepath.Join(dir, b.name)
+		if err := os.WriteFile(binary, data, 0755); err != nil {
+			return err
+		}
+		// The umask may have dropped the executable bits
+		if err := os.Chmod(binary, 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get the path of a backend binary, extracting the embedded binaries on first use
+func getBackendBinaryName(name string) (string, error) {
+	extractOnce.Do(func() { extractErr = extractBackends() })
+	if extractErr != nil {
+		return "", extractErr
+	}
+	return filepath.Join(extractedDir, name), nil
+}
+
+// Remove the extracted backend binaries
+func cleanupBackendBinary() {
+	if extractedDir != "" {
+		os.RemoveAll(extractedDir)
+	}
+}
+{{else}}
+// Get the path of a backend binary next to the bundle executable, so the
+// bundle works regardless of the current working directory
+func getBackendBinaryName(name string) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(exe), name), nil
+}
+
+// The backend binaries ship alongside the bundle, so there is nothing to remove
+func cleanupBackendBinary() {}
+{{end}}
+
+// Readiness probe settings for the backend process
+const (
+	backendReadyTimeout = time.Duration({{printf "%d" .BackendReadyTimeout}})
+	backendHealthPath   = {{printf "%q" .BackendHealthPath}}
+)
+
+// Wait until the backend is ready or the timeout elapses
+func waitForBackend(timeout time.Duration) error {
+	addr := "127.0.0.1:" + getBackendPort()
+	client := &http.Client{Timeout: time.Second}
+	deadline := time.Now().Add(timeout)
+	for !backendReady(client, addr) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("backend at %s was not ready after %s", addr, timeout)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return nil
+}
+
+// Check the backend health path, or that its port accepts connections when no path is set
+func backendReady(client *http.Client, addr string) bool {
+	if backendHealthPath == "" {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+
+	res, err := client.Get("http://" + addr + backendHealthPath)
+	if err != nil {
+		return false
+	}
+	res.Body.Close()
+	return res.StatusCode >= 200 && res.StatusCode < 300
+}
+
+// Start the backend process at index i of backends
+func startBackend(i int) (*exec.Cmd, error) {
+	b := backends[i]
+	port := b.port
+	if i == 0 {
+		port = getBackendPort()
+	}
+	log.Printf("Starting backend process %s on port %s...", b.name, port)
+	var cmd *exec.Cmd
+	if len(b.command) > 0 {
+		cmd = exec.Command(b.command[0], b.command[1:]...)
+	} else {
+		binary, err := getBackendBinaryName(b.name)
+		if err != nil {
+			return nil, err
+		}
+		cmd = exec.Command(binary)
+	}
+	cmd.Env = append(os.Environ(), "BACKEND_PORT="+port)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	if err := trackProcessTree(cmd); err != nil {
+		log.Printf("WARNING: processes started by backend %s may outlive it: %v", b.name, err)
+	}
+	return cmd, nil
+}
+{{if eq .GOOS "windows"}}
+// Windows has no process groups to signal; Ctrl-C reaches the backend
+// through the shared console
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// Send sig to the backend process
+func signalProcessGroup(p *os.Process, sig os.Signal) error {
+	return p.Signal(sig)
+}
+
+var (
+	kernel32                     = syscall.NewLazyDLL("kernel32.dll")
+	procCreateJobObjectW         = kernel32.NewProc("CreateJobObjectW")
+	procSetInformationJobObject  = kernel32.NewProc("SetInformationJobObject")
+	procAssignProcessToJobObject = kernel32.NewProc("AssignProcessToJobObject")
+	procTerminateJobObject       = kernel32.NewProc("TerminateJobObject")
+)
+
+const (
+	jobObjectExtendedLimitInformation = 9
+	jobObjectLimitKillOnJobClose      = 0x2000
+	processSetQuota                   = 0x0100
+)
+
+// JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+type jobExtendedLimitInformation struct {
+	PerProcessUserTimeLimit int64
+	PerJobUserTimeLimit     int64
+	LimitFlags              uint32
+	MinimumWorkingSetSize   uintptr
+	MaximumWorkingSetSize   uintptr
+	ActiveProcessLimit      uint32
+	Affinity                uintptr
+	PriorityClass           uint32
+	SchedulingClass         uint32
+	IoInfo                  [6]uint64
+	ProcessMemoryLimit      uintptr
+	JobMemoryLimit          uintptr
+	PeakProcessMemoryUsed   uintptr
+	PeakJobMemoryUsed       uintptr
+}
+
+// Job objects holding each backend process and its descendants, keyed by PID
+var processJobs sync.Map
+
+// Put the backend into a job object, which its child processes join too.
+// The job kills them all when terminated or when the bundle exits.
+func trackProcessTree(cmd *exec.Cmd) error {
+	job, _, err := procCreateJobObjectW.Call(0, 0)
+	if job == 0 {
+		return err
+	}
+	info := jobExtendedLimitInformation{LimitFlags: jobObjectLimitKillOnJobClose}
+	if ok, _, err := procSetInformationJobObject.Call(job, jobObjectExtendedLimitInformation, uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info)); ok == 0 {
+		syscall.CloseHandle(syscall.Handle(job))
+		return err
+	}
+	process, err := syscall.OpenProcess(processSetQuota|syscall.PROCESS_TERMINATE, false, uint32(cmd.Process.Pid))
+	if err != nil {
+		syscall.CloseHandle(syscall.Handle(job))
+		return err
+	}
+	defer syscall.CloseHandle(process)
+	if ok, _, err := procAssignProcessToJobObject.Call(job, uintptr(process)); ok == 0 {
+		syscall.CloseHandle(syscall.Handle(job))
+		return err
+	}
+	processJobs.Store(cmd.Process.Pid, syscall.Handle(job))
+	return nil
+}
+
+// Kill the backend and every process it started through its job object
+func killProcessTree(p *os.Process) {
+	job, ok := processJobs.LoadAndDelete(p.Pid)
+	if !ok {
+		p.Kill()
+		return
+	}
+	procTerminateJobObject.Call(uintptr(job.(syscall.Handle)), 1)
+	syscall.CloseHandle(job.(syscall.Handle))
+}
+{{else}}
+// Start the backend in its own process group, so shutdown signals reach the
+// processes it spawns too. The group no longer receives the terminal's
+// Ctrl-C, which the supervisor forwards instead.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// Send sig to every process in the backend's process group
+func signalProcessGroup(p *os.Process, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return p.Signal(sig)
+	}
+	return syscall.Kill(-p.Pid, s)
+}
+
+// The process group set up by setProcessGroup already tracks the processes
+// the backend starts
+func trackProcessTree(cmd *exec.Cmd) error {
+	return nil
+}
+
+// Kill every process in the backend's process group, including ones left
+// behind after the backend itself exited
+func killProcessTree(p *os.Process) {
+	syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
+{{end}}
+// Restart policy for the supervised backend process
+const (
+	backendRestart    = {{.BackendRestart}}
+	minRestartBackoff = time.Second
+	maxRestartBackoff = 30 * time.Second
+
+	// How long the backend may take to exit after SIGTERM before it is killed
+	backendGracePeriod = time.Duration({{printf "%d" .BackendGracePeriod}})
+)
+
+// Supervises the backend process, restarting it if it exits unexpectedly
+type backendSupervisor struct {
+	index    int // position in backends
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	running  bool
+	received os.Signal // shutdown signal forwarded to the backend
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func newBackendSupervisor(index int, cmd *exec.Cmd) *backendSupervisor {
+	s := &backendSupervisor{
+		index:   index,
+		cmd:     cmd,
+		running: true,
+		stopCh: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	// Stop restarting as soon as a shutdown signal arrives, and remember it
+	// so stop forwards the same signal to the backend. Once the supervisor is
+	// done, the signal is no longer needed.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(sig)
+		select {
+		case received := <-sig:
+			s.mu.Lock()
+			s.received = received
+			s.mu.Unlock()
+			s.halt()
+		case <-s.done:
+		}
+	}()
+
+	go s.run()
+	return s
+}
+
+// Get the currently running backend process
+func (s *backendSupervisor) process() *exec.Cmd {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cmd
+}
+
+// Report whether the backend process is currently running
+func (s *backendSupervisor) alive() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// Stop restarting the backend without terminating it
+func (s *backendSupervisor) halt() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+}
+
+func (s *backendSupervisor) stopping() bool {
+	select {
+	case <-s.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop supervising and terminate the backend with the shutdown signal the
+// bundle received (SIGTERM by default), killing it if it does not exit
+// within the grace period
+func (s *backendSupervisor) stop() {
+	s.halt()
+	s.signal(s.shutdownSignal())
+
+	select {
+	case <-s.done:
+	case <-time.After(backendGracePeriod):
+		log.Printf("Backend %s did not exit within %s, killing it", backends[s.index].name, backendGracePeriod)
+		s.signal(os.Kill)
+		<-s.done
+	}
+}
+
+// The signal stop sends to the backend
+func (s *backendSupervisor) shutdownSignal() os.Signal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.received != nil {
+		return s.received
+	}
+	return syscall.SIGTERM
+}
+
+// Send a signal to the backend's process group, falling back to killing the
+// process tree where the signal is unsupported (e.g. SIGTERM on Windows)
+func (s *backendSupervisor) signal(sig os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cmd == nil || s.cmd.Process == nil {
+		return
+	}
+	if sig == os.Kill || signalProcessGroup(s.cmd.Process, sig) != nil {
+		killProcessTree(s.cmd.Process)
+	}
+}
+
+// Wait for the backend to exit and restart it with capped exponential backoff
+func (s *backendSupervisor) run() {
+	defer close(s.done)
+
+	backoff := minRestartBackoff
+	for {
+		started := time.Now()
+		cmd := s.process()
+		err := cmd.Wait()
+		// Don't leak processes the backend started but didn't stop
+		killProcessTree(cmd.Process)
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+		if s.stopping() {
+			return
+		}
+		log.Printf("Backend process %s exited unexpectedly: %v", backends[s.index].name, err)
+		if !backendRestart {
+			return
+		}
+
+		// Reset the backoff once the backend has stayed up for a while
+		if time.Since(started) > maxRestartBackoff {
+			backoff = minRestartBackoff
+		}
+
+		for {
+			log.Printf("Restarting backend %s in %s...", backends[s.index].name, backoff)
+			select {
+			case <-s.stopCh:
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > maxRestartBackoff {
+				backoff = maxRestartBackoff
+			}
+
+			if s.restart() {
+				break
+			}
+		}
+	}
+}
+
+// Start a new backend process, reporting whether it is running
+func (s *backendSupervisor) restart() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopping() {
+		return false
+	}
+
+	cmd, err := startBackend(s.index)
+	if err != nil {
+		log.Printf("Failed to restart backend %s: %v", backends[s.index].name, err)
+		return false
+	}
+	s.cmd = cmd
+	s.running = true
+	return true
+}
+
+// Stop the supervised backends concurrently, so each gets the full grace period
+func stopBackends(supervisors []*backendSupervisor) {
+	var wg sync.WaitGroup
+	for _, s := range supervisors {
+		wg.Add(1)
+		go func(s *backendSupervisor) {
+			defer wg.Done()
+			s.stop()
+		}(s)
+	}
+	wg.Wait()
+}
+
+{{end}}
+{{if .Backend}}
+// CORS settings for proxied API requests; disabled when no origins are allowed
+var corsOrigins = []string{ {{- range $i, $origin := .CORSOrigins}}{{if $i}}, {{end}}{{printf "%q" $origin}}{{end}}}
+
+const (
+	corsMethods = {{printf "%q" .CORSMethods}}
+	corsHeaders = {{printf "%q" .CORSHeaders}}
+)
+
+// Report whether requests from origin may read API responses
+func corsAllowed(origin string) bool {
+	for _, allowed := range corsOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Wrap the API proxy with CORS headers for allowed origins, answering
+// preflight requests without reaching the backend
+func corsHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		if origin == "" || !corsAllowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Methods", corsMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsHeaders)
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+{{end}}
+// Set up the routes: the API proxy and each embedded frontend at its prefix
+func startServer() (*http.ServeMux, error) {
+	mux := http.NewServeMux()
+
+{{if .Backend}}
+	// Proxy API requests to the backend process
+	if apiPrefix != "" {
+		backendURL := &url.URL{Scheme: "http", Host: "127.0.0.1:" + getBackendPort()}
+		var proxy http.Handler = httputil.NewSingleHostReverseProxy(backendURL)
+		if len(corsOrigins) > 0 {
+			proxy = corsHandler(proxy)
+		}
+		mux.Handle(apiPrefix, proxy)
+		mux.Handle(apiPrefix+"/", proxy)
+		log.Printf("Proxying %s requests to the backend at %s", apiPrefix, backendURL)
+	}
+{{end}}
+
+	for _, f := range frontends {
+		fsys, err := fs.Sub(frontendFS, f.dir)
+		if err != nil {
+			return nil, err
+		}
+		if f.assets != "" && !hasFiles(fsys, f.assets) {
+			log.Printf("WARNING: %s/%s is missing or empty in the embedded frontend; pages will load without their scripts and styles", f.dir, f.assets)
+		}
+		if f.prefix == "" {
+			mux.Handle("/", frontendHandler(fsys))
+		} else {
+			mux.Handle(f.prefix+"/", http.StripPrefix(f.prefix, frontendHandler(fsys)))
+		}
+		log.Printf("Serving frontend %s at %s/", f.dir, f.prefix)
+	}
+
+	return mux, nil
+}
+
+// Content types for web assets that a host's mime database may lack or get
+// wrong; browsers refuse module scripts and WebAssembly served with the wrong type
+var webContentTypes = map[string]string{
+	".js":          "text/javascript; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".wasm":        "application/wasm",
+	".json":        "application/json",
+	".webmanifest": "application/manifest+json",
+	".map":         "application/json",
+}
+
+// Register webContentTypes before anything is served, overriding the host's
+func init() {
+	for ext, ctype := range webContentTypes {
+		if err := mime.AddExtensionType(ext, ctype); err != nil {
+			log.Fatalf("Failed to register content type for %s: %v", ext, err)
+		}
+	}
+}
+
+// Report whether dir exists in fsys and contains at least one file
+func hasFiles(fsys fs.FS, dir string) bool {
+	found := false
+	fs.WalkDir(fsys, dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			found = true
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// File server for one embedded frontend, with its own SPA fallback
+func frontendHandler(fsys fs.FS) http.Handler {
+	if compressedAssets {
+		fsys = gzipFS{fsys}
+	}
+
+	// Serve all static files using http.FileServer
+	fileServer := http.FileServer(http.FS(fsys))
+	var static http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Try to serve the requested file
+		if fileExists(fsys, r.URL.Path) {
+			if strings.HasSuffix(r.URL.Path, "/index.html") {
+				// http.FileServer would redirect these to the directory
+				serveFile(w, r, fsys, strings.TrimPrefix(r.URL.Path, "/"))
+				return
+			}
+			fileServer.ServeHTTP(w, r)
+		} else if !servesIndexFallback(r) {
+			serveNotFound(w, r, fsys)
+		} else {
+			// If the file doesn't exist, serve the SPA entry for client-side routing
+			serveFile(w, r, fsys, spaEntry)
+		}
+	})
+
+	// Compress static responses for clients that support it
+	if compressionEnabled {
+		static = compressionHandler(fsys, static)
+	}
+	return prettyURLHandler(fsys, cachingHandler(fsys, static))
+}
+
+// Serve an embedded file by name with http.ServeContent
+func serveFile(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		http.Error(w, name+" not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		http.Error(w, "failed to read "+name, http.StatusInternalServerError)
+		return
+	}
+
+	// Embedded files have no mod-time, so use the build time for a stable Last-Modified
+	modTime := buildTime
+	if info, err := f.Stat(); err == nil && !info.ModTime().IsZero() {
+		modTime = info.ModTime()
+	}
+
+	// bytes.Reader implements the io.ReadSeeker ServeContent needs
+	http.ServeContent(w, r, path.Base(name), modTime, bytes.NewReader(content))
+}
+
+// Canonical form of directory URLs, matching Next.js trailingSlash: with it,
+// /about redirects to /about/; without it, /about/ redirects to /about
+const trailingSlash = {{.TrailingSlash}}
+
+// Resolve extensionless URLs of missing files to the page they name: a
+// directory's index.html (e.g. /about to about/index.html from a trailingSlash
+// export, after redirecting to the canonical form), or else <path>.html
+func prettyURLHandler(fsys fs.FS, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dir := strings.TrimSuffix(r.URL.Path, "/")
+		if dir == "" || fileExists(fsys, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !fileExists(fsys, dir+"/index.html") {
+			if path.Ext(dir) == "" && !strings.HasSuffix(r.URL.Path, "/") && fileExists(fsys, dir+".html") {
+				r = withPath(r, dir+".html")
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/") != trailingSlash {
+			// Redirect relative to the request, so mounts under a prefix keep it
+			target := "../" + path.Base(dir)
+			if trailingSlash {
+				target = path.Base(dir) + "/"
+			}
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			w.Header().Set("Location", target)
+			w.WriteHeader(http.StatusPermanentRedirect)
+			return
+		}
+		next.ServeHTTP(w, withPath(r, dir+"/index.html"))
+	})
+}
+
+// Return a shallow copy of r for another URL path
+func withPath(r *http.Request, urlPath string) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = urlPath
+	r2.URL.RawPath = ""
+	return r2
+}
+
+// Serve spaEntry for missing paths so client-side routes work; when
+// disabled, missing paths get a real 404
+const spaFallback = {{.SPAFallback}}
+
+// Only fall back for browser navigation, i.e. requests that accept HTML
+const strictRoutes = {{.StrictRoutes}}
+
+// Page served for client-side routes, e.g. index.html or 200.html
+const spaEntry = {{printf "%q" .SPAEntry}}
+
+// Report whether a missing path gets spaEntry. Paths with a file extension
+// (even with a trailing slash) are missing assets rather than client-side
+// routes, and Range requests come from media players rather than page
+// navigation, so both get a 404. With strictRoutes, so do requests that
+// don't accept HTML, such as fetch calls and crawlers probing for files.
+func servesIndexFallback(r *http.Request) bool {
+	if !spaFallback || path.Ext(strings.TrimSuffix(r.URL.Path, "/")) != "" || r.Header.Get("Range") != "" {
+		return false
+	}
+	return !strictRoutes || acceptsHTML(r)
+}
+
+// Check whether the request's Accept header lists text/html
+func acceptsHTML(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		if strings.EqualFold(strings.TrimSpace(mediaType), "text/html") {
+			return true
+		}
+	}
+	return false
+}
+
+// Respond with 404, using the embedded 404.html when present
+func serveNotFound(w http.ResponseWriter, r *http.Request, fsys fs.FS) {
+	content, err := fs.ReadFile(fsys, "404.html")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	w.Write(content)
+}
+
+// Check if a file exists in the embedded filesystem
+func fileExists(fsys fs.FS, filePath string) bool {
+	if filePath == "/" {
+		filePath = "index.html" // Serve index.html if root is requested
+	}
+
+	// Directories don't count, so client-side routes that collide with
+	// directory names fall back to index.html
+	info, err := fs.Stat(fsys, strings.TrimPrefix(filePath, "/"))
+	return err == nil && !info.IsDir()
+}
+
+// Assets were gzipped at build time and are stored as <name>.gz
+const compressedAssets = {{.CompressedAssets}}
+
+// Filesystem that transparently decompresses assets stored as <name>.gz
+type gzipFS struct {
+	fs.FS
+}
+
+func (g gzipFS) Open(name string) (fs.File, error) {
+	f, err := g.FS.Open(name)
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return f, err
+	}
+
+	gz, gzErr := g.FS.Open(name + ".gz")
+	if gzErr != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	info, err := gz.Stat()
+	if err != nil {
+		return nil, err
+	}
+	r, err := gzip.NewReader(gz)
+	if err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &memFile{
+		Reader: bytes.NewReader(content),
+		info:   memFileInfo{name: path.Base(name), size: int64(len(content)), modTime: info.ModTime()},
+	}, nil
+}
+
+// In-memory file holding decompressed asset content
+type memFile struct {
+	*bytes.Reader
+	info memFileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() fs.FileMode  { return 0444 }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return false }
+func (i memFileInfo) Sys() any           { return nil }
+
+// Paths matching this pattern are content-hashed and cached forever
+var immutablePattern = {{if .ImmutablePattern}}regexp.MustCompile({{printf "%q" .ImmutablePattern}}){{else}}(*regexp.Regexp)(nil){{end}}
+
+// Map a request path to the file it refers to in the embedded filesystem
+func staticFileName(urlPath string) string {
+	name := strings.TrimPrefix(urlPath, "/")
+	if name == "" || strings.HasSuffix(name, "/") {
+		name += "index.html"
+	}
+	return name
+}
+
+// Wrap a static handler with Cache-Control and content-hash ETag headers so
+// fingerprinted assets are cached forever and HTML is always revalidated
+func cachingHandler(fsys fs.FS, next http.Handler) http.Handler {
+	// Content-hash ETags keyed by file name
+	var etags sync.Map
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := spaEntry
+		exists := fileExists(fsys, r.URL.Path)
+		if !exists && !servesIndexFallback(r) {
+			// Missing paths are served as 404s, not as the SPA entry
+			next.ServeHTTP(w, r)
+			return
+		}
+		if exists {
+			name = staticFileName(r.URL.Path)
+		}
+
+		if etag, ok := etagFor(&etags, fsys, name); ok {
+			// Compressed and uncompressed responses share a weak ETag. Other
+			// files keep a strong one, which If-Range needs to resume media.
+			if compressionEnabled && compressible(mime.TypeByExtension(path.Ext(name))) {
+				etag = "W/" + etag
+			}
+			w.Header().Set("ETag", etag)
+		}
+		switch {
+		case exists && immutablePattern != nil && immutablePattern.MatchString(r.URL.Path):
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		case strings.HasSuffix(name, ".html"):
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Get the strong ETag for an embedded file, hashing its content on first use
+func etagFor(etags *sync.Map, fsys fs.FS, name string) (string, bool) {
+	if etag, ok := etags.Load(name); ok {
+		return etag.(string), true
+	}
+
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(content)
+	etag := fmt.Sprintf("\"%x\"", sum[:16])
+	etags.Store(name, etag)
+	return etag, true
+}
+
+// Compression settings for static responses
+const (
+	compressionEnabled = {{.Compression}}
+	compressionMinSize = 1024
+)
+
+// Pre-compressed sibling files served in order of preference
+var precompressedEncodings = []struct {
+	Encoding  string
+	Extension string
+}{
+	{Encoding: "br", Extension: ".br"},
+	{Encoding: "gzip", Extension: ".gz"},
+}
+
+// Wrap a static handler so it serves pre-compressed .br/.gz siblings when
+// present and otherwise gzips compressible responses on the fly
+func compressionHandler(fsys fs.FS, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if fileExists(fsys, r.URL.Path) && servePrecompressed(w, r, fsys) {
+			return
+		}
+		if !acceptsEncoding(r, "gzip") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer gw.Close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// Serve a pre-compressed sibling of the requested file if the client accepts its encoding
+func servePrecompressed(w http.ResponseWriter, r *http.Request, fsys fs.FS) bool {
+	name := staticFileName(r.URL.Path)
+	for _, pre := range precompressedEncodings {
+		if !acceptsEncoding(r, pre.Encoding) {
+			continue
+		}
+
+		f, err := fsys.Open(name + pre.Extension)
+		if err != nil {
+			continue
+		}
+		info, err := f.Stat()
+		content, ok := f.(io.ReadSeeker)
+		if err != nil || info.IsDir() || !ok {
+			f.Close()
+			continue
+		}
+		defer f.Close()
+
+		if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+			w.Header().Set("Content-Type", ctype)
+		}
+		w.Header().Set("Content-Encoding", pre.Encoding)
+		http.ServeContent(w, r, name, info.ModTime(), content)
+		return true
+	}
+	return false
+}
+
+// Check whether the request's Accept-Encoding allows the given encoding
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		fields := strings.Split(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(fields[0]), encoding) {
+			continue
+		}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); strings.HasPrefix(param, "q=") && err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Check whether a content type benefits from compression
+func compressible(contentType string) bool {
+	ct := strings.ToLower(contentType)
+	return strings.HasPrefix(ct, "text/") ||
+		strings.Contains(ct, "javascript") ||
+		strings.Contains(ct, "json") ||
+		strings.Contains(ct, "xml") ||
+		strings.Contains(ct, "wasm")
+}
+
+// ResponseWriter that buffers the start of a response and gzips it once it
+// is known to be large enough and compressible
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz      *gzip.Writer
+	buf     []byte
+	status  int
+	decided bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if !w.decided {
+		w.status = status
+	}
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= compressionMinSize {
+		if err := w.flushBuffer(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Decide whether to compress and write out the buffered bytes
+func (w *gzipResponseWriter) flushBuffer() error {
+	w.decided = true
+
+	h := w.Header()
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if w.status == http.StatusOK && len(w.buf) >= compressionMinSize &&
+		h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// Flush any buffered response and finish the gzip stream
+func (w *gzipResponseWriter) Close() error {
+	if !w.decided {
+		if err := w.flushBuffer(); err != nil {
+			return err
+		}
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
+
+// Path of the health endpoint, or empty to disable it
+const healthzPath = {{printf "%q" .HealthzPath}}
+{{if .Backend}}
+// How long a health check result is reused, so frequent probes don't hammer
+// the backend; zero checks on every request
+const healthzCacheTTL = time.Duration({{printf "%d" .HealthzCacheTTL}})
+
+// Report 200 when every backend process is running and the proxied backend
+// responds to the readiness probe, and 503 otherwise
+func healthzHandler(supervisors []*backendSupervisor) http.Handler {
+	client := &http.Client{Timeout: time.Second}
+	var (
+		mu      sync.Mutex
+		checked time.Time
+		status  int
+		message string
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Concurrent probes wait for one check instead of each running their own
+		mu.Lock()
+		if checked.IsZero() || time.Since(checked) >= healthzCacheTTL {
+			status, message = checkHealth(client, supervisors)
+			checked = time.Now()
+		}
+		code, msg := status, message
+		mu.Unlock()
+
+		if code != http.StatusOK {
+			http.Error(w, msg, code)
+			return
+		}
+		fmt.Fprintln(w, msg)
+	})
+}
+
+// Check the backend processes and the proxied backend's health path
+func checkHealth(client *http.Client, supervisors []*backendSupervisor) (int, string) {
+	for _, s := range supervisors {
+		if !s.alive() {
+			return http.StatusServiceUnavailable, "backend " + backends[s.index].name + " is not running"
+		}
+	}
+	if !backendReady(client, "127.0.0.1:"+getBackendPort()) {
+		return http.StatusServiceUnavailable, "backend is not responding"
+	}
+	return http.StatusOK, "ok"
+}
+{{else}}
+// Report 200 while the server is up; there is no backend to check
+func healthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+}
+{{end}}
+{{if .ManifestPath}}
+// Path of the JSON manifest describing the bundle
+const manifestPath = {{printf "%q" .ManifestPath}}
+
+// Serve a JSON description of the bundle for service-discovery tooling
+func manifestHandler() http.Handler {
+	var names []string{{if .Backend}}
+	for _, b := range backends {
+		names = append(names, b.name)
+	}{{end}}
+	var prefixes []string
+	for _, f := range frontends {
+		prefixes = append(prefixes, f.prefix+"/")
+	}
+	body, err := json.MarshalIndent(map[string]any{
+		"version":   appVersion,
+		"commit":    gitCommit,
+		"buildDate": buildDate,
+		"gonext":    gonextVersion,
+		"backend":   len(names) > 0,
+		"backends":  names,
+		"routes": map[string]any{
+			"basePath":  basePath,
+			"apiPrefix": {{if .Backend}}apiPrefix{{else}}""{{end}},
+			"health":    healthzPath,
+			"metrics":   {{if .Metrics}}metricsPath{{else}}""{{end}},
+			"manifest":  manifestPath,
+			"frontends": prefixes,
+		},
+	}, "", "  ")
+	if err != nil {
+		log.Printf("Failed to encode the manifest: %v", err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+}
+{{end}}
+// Wrap a handler so every request needs matching basic-auth credentials.
+// The health endpoint stays open so probes work without credentials. It runs
+// before basePath is stripped, so the health endpoint is matched under it.
+func basicAuthHandler(user, pass string, next http.Handler) http.Handler {
+	// Compare fixed-size hashes so neither the values nor their lengths leak through timing
+	wantUser, wantPass := sha256.Sum256([]byte(user)), sha256.Sum256([]byte(pass))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if healthzPath != "" && r.URL.Path == basePath+healthzPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		u, p, ok := r.BasicAuth()
+		gotUser, gotPass := sha256.Sum256([]byte(u)), sha256.Sum256([]byte(p))
+		userOK := subtle.ConstantTimeCompare(gotUser[:], wantUser[:])
+		passOK := subtle.ConstantTimeCompare(gotPass[:], wantPass[:])
+		if !ok || userOK&passOK != 1 {
+			w.Header().Set("WWW-Authenticate", "Basic realm=\"Restricted\", charset=\"UTF-8\"")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Path prefix the bundle is served under, or empty when it owns the site root
+const basePath = {{printf "%q" .BasePath}}
+
+// Strip basePath from incoming requests so the routes below see paths relative
+// to it. The bare prefix redirects to its slash form so relative asset URLs
+// resolve under it; anything outside the prefix is not found.
+func basePathHandler(next http.Handler) http.Handler {
+	strip := http.StripPrefix(basePath, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == basePath {
+			target := basePath + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusPermanentRedirect)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, basePath+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		strip.ServeHTTP(w, r)
+	})
+}
+
+// Log one line per request with method, path, status and latency
+const accessLog = {{.AccessLog}}
+
+// Wrap a handler so each request is written to the access log
+func accessLogHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		log.Printf("%s %s %d %s", r.Method, r.URL.RequestURI(), rec.status, time.Since(start))
+	})
+}
+
+// ResponseWriter that records the status code sent to the client
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
+}
+{{if .Metrics}}
+// Path of the Prometheus metrics endpoint
+const metricsPath = "/metrics"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gonext_http_requests_total",
+		Help: "HTTP requests served, by method and status code.",
+	}, []string{"method", "code"})
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gonext_http_request_duration_seconds",
+		Help:    "Latency of HTTP requests, by method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// Wrap a handler so each request is counted and timed
+func metricsHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		httpRequests.WithLabelValues(r.Method, strconv.Itoa(rec.status)).Inc()
+		httpRequestDuration.WithLabelValues(r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+{{if .Backend}}
+// Export whether each backend process is running as gonext_backend_up
+func registerBackendMetrics(supervisors []*backendSupervisor) {
+	for _, s := range supervisors {
+		s := s
+		promauto.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "gonext_backend_up",
+			Help:        "Whether the backend process is running (1) or not (0).",
+			ConstLabels: prometheus.Labels{"backend": backends[s.index].name},
+		}, func() float64 {
+			if s.alive() {
+				return 1
+			}
+			return 0
+		})
+	}
+}
+{{end}}{{end}}
+// Let http.ResponseController reach the underlying writer, e.g. to flush
+// streamed proxy responses or hijack the connection for WebSocket upgrades
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// TLS certificate and key files, read at runtime; plain HTTP is served when empty
+const (
+	tlsCertFile = {{printf "%q" .TLSCert}}
+	tlsKeyFile  = {{printf "%q" .TLSKey}}
+)
+
+// Server timeouts guarding against slow clients holding connections open; 0 disables one
+const (
+	readHeaderTimeout = time.Duration({{printf "%d" .ReadHeaderTimeout}})
+	readTimeout       = time.Duration({{printf "%d" .ReadTimeout}})
+	writeTimeout      = time.Duration({{printf "%d" .WriteTimeout}})
+	idleTimeout       = time.Duration({{printf "%d" .IdleTimeout}})
+)
+
+// How long in-flight requests may take to finish on shutdown
+const shutdownTimeout = time.Duration({{printf "%d" .ShutdownTimeout}})
+
+// Returns the shutdown timeout, overridable with the SHUTDOWN_TIMEOUT environment variable
+func getShutdownTimeout() time.Duration {
+	if value := os.Getenv("SHUTDOWN_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err == nil && timeout >= 0 {
+			return timeout
+		}
+		log.Printf("Ignoring invalid SHUTDOWN_TIMEOUT %q, using %s", value, shutdownTimeout)
+	}
+	return shutdownTimeout
+}
+
+// Binds the server address, explaining the common case of a port that is
+// already taken
+func listen(host, port string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if errors.Is(err, syscall.EADDRINUSE) {
+		if pid := portOwner(port); pid != "" {
+			return nil, fmt.Errorf("port %s already in use by PID %s; stop it or set PORT to another port", port, pid)
+		}
+		return nil, fmt.Errorf("port %s already in use; stop the process using it or set PORT to another port", port)
+	}
+	return ln, err
+}
+
+// Finds the PID listening on a TCP port through /proc, returning "" when it
+// can't be determined (e.g. on other platforms or without permission)
+func portOwner(port string) string {
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return ""
+	}
+	hexPort := fmt.Sprintf(":%04X", n)
+
+	inodes := map[string]bool{}
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		data, err := os.ReadFile(table)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n")[1:] {
+			// Fields: sl local_address rem_address st ... uid timeout inode
+			fields := strings.Fields(line)
+			if len(fields) > 9 && strings.HasSuffix(fields[1], hexPort) && fields[3] == "0A" {
+				inodes["socket:["+fields[9]+"]"] = true
+			}
+		}
+	}
+	if len(inodes) == 0 {
+		return ""
+	}
+
+	procs, _ := os.ReadDir("/proc")
+	for _, proc := range procs {
+		if _, err := strconv.Atoi(proc.Name()); err != nil {
+			continue
+		}
+		fdDir := "/proc/" + proc.Name() + "/fd"
+		fds, _ := os.ReadDir(fdDir)
+		for _, fd := range fds {
+			if link, err := os.Readlink(fdDir + "/" + fd.Name()); err == nil && inodes[link] {
+				return proc.Name()
+			}
+		}
+	}
+	return ""
+}
+
+// StartHTTPServer starts the HTTP server on ln with graceful shutdown. It returns
+// when the server is stopped by a signal, or with an error if it fails.
+func startHTTPServer(server *http.Server, ln net.Listener) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	useTLS := tlsCertFile != "" && tlsKeyFile != ""
+	serveErr := make(chan error, 1)
+	go func() {
+		var err error
+		if useTLS {
+			err = server.ServeTLS(ln, tlsCertFile, tlsKeyFile)
+		} else {
+			err = server.Serve(ln)
+		}
+		serveErr <- err
+	}()
+
+	if useTLS {
+		log.Println("HTTPS server (HTTP/2 enabled) is running on", server.Addr)
+	} else {
+		log.Println("HTTP server is running on", server.Addr)
+	}
+
+	select {
+	case err := <-serveErr:
+		if err != http.ErrServerClosed {
+			return fmt.Errorf("HTTP server failed: %w", err)
+		}
+		return nil
+	case <-stop:
+	}
+
+	log.Println("Shutting down HTTP server...")
+	ctx, cancel := context.WithTimeout(context.Background(), getShutdownTimeout())
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("HTTP server graceful shutdown failed: %w", err)
+	}
+	log.Println("HTTP server stopped.")
+	return nil
+}
+
+// Build metadata, stamped in with -ldflags -X when GoNext builds the bundle
+var (
+	appVersion    = "dev"
+	gitCommit     = "unknown"
+	buildDate     = "unknown"
+	gonextVersion = "unknown"
+)
+
+// Print the build metadata
+func printVersion() {
+	fmt.Printf("version: %s\ncommit:  %s\nbuilt:   %s\ngonext:  %s\n", appVersion, gitCommit, buildDate, gonextVersion)
+}
+
+{{if .BundleInfo}}
+// Build information read by gonext inspect: a marker, then JSON up to a NUL byte
+const bundleInfo = {{printf "%q" .BundleInfo}}
+{{end}}
+func main() {
+	if len(os.Args) > 1 && (os.Args[1] == "version" || os.Args[1] == "--version") {
+		printVersion()
+		return
+	}
+{{- if .BundleInfo}}
+	if len(os.Args) > 1 && os.Args[1] == "bundle-info" {
+		_, info, _ := strings.Cut(bundleInfo, ":")
+		fmt.Println(strings.TrimSuffix(info, "\x00"))
+		return
+	}
+{{- end}}
+
+	// run's deferred cleanup stops the backends before we exit non-zero
+	if err := run(); err != nil {
+		log.Printf("%v", err)
+		os.Exit(1)
+	}
+}
+
+{{if .Dotenv}}
+// Load runtime configuration from the file in DOTENV_PATH, or from a .env
+// file next to the executable when present. Variables that are already set
+// win, and the backends inherit the result.
+func loadDotenv() error {
+	name := os.Getenv("DOTENV_PATH")
+	if name == "" {
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		name = filepath.Join(filepath.Dir(exe), ".env")
+		if _, err := os.Stat(name); errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return fmt.Errorf("%s:%d: expected KEY=VALUE", name, i+1)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value = strings.NewReplacer("\\n", "\n", "\\\"", "\"", "\\\\", "\\").Replace(value[1 : len(value)-1])
+		default:
+			// Unquoted values may end with a comment
+			if j := strings.Index(value, " #"); j >= 0 {
+				value = strings.TrimSpace(value[:j])
+			}
+		}
+		if _, set := os.LookupEnv(key); !set {
+			os.Setenv(key, value)
+		}
+	}
+	log.Printf("Loaded environment from %s", name)
+	return nil
+}
+{{end}}
+// Starts the backends and serves the bundle until shutdown
+func run() error {
+{{- if .Dotenv}}
+	if err := loadDotenv(); err != nil {
+		return err
+	}
+{{- end}}
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "{{.Port}}"
+	}
+	// Bind address host; empty listens on all interfaces
+	host := os.Getenv("HOST")
+	if host == "" {
+		host = {{printf "%q" .Host}}
+	}
+
+	// Claim the port before launching backends, so a taken port fails fast
+	ln, err := listen(host, port)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+{{if .Backend}}
+	// Start backend processes
+	var supervisors []*backendSupervisor
+	// Ensure backend processes are stopped when the application shuts down,
+	// then remove the extracted backend binaries
+	defer cleanupBackendBinary()
+	defer func() { stopBackends(supervisors) }()
+	for i := range backends {
+		cmd, err := startBackend(i)
+		if err != nil {
+			return fmt.Errorf("failed to start backend %s: %w", backends[i].name, err)
+		}
+		supervisors = append(supervisors, newBackendSupervisor(i, cmd))
+	}
+
+	// Wait for the proxied backend before accepting traffic
+	if backendReadyTimeout > 0 {
+		if err := waitForBackend(backendReadyTimeout); err != nil {
+			return fmt.Errorf("backend failed to become ready: %w", err)
+		}
+		log.Println("Backend is ready")
+	}
+
+{{end}}
+	// Setup frontend server
+	mux, err := startServer()
+	if err != nil {
+		return fmt.Errorf("failed to start frontend server: %w", err)
+	}
+
+	// Health endpoint for load balancers and orchestrators
+	if healthzPath != "" {
+		mux.Handle(healthzPath, healthzHandler({{if .Backend}}supervisors{{end}}))
+	}
+{{if .Metrics}}
+	// Prometheus metrics; the mux prefers this exact path over the frontend catch-all
+	mux.Handle(metricsPath, promhttp.Handler())
+{{- if .Backend}}
+	registerBackendMetrics(supervisors)
+{{- end}}
+	log.Printf("Serving metrics at %s", metricsPath)
+{{end}}{{if .ManifestPath}}
+	mux.Handle(manifestPath, manifestHandler())
+{{end}}
+	var handler http.Handler = mux
+	if basePath != "" {
+		handler = basePathHandler(handler)
+		log.Printf("Serving under base path %s", basePath)
+	}
+	// Credentials are only read at runtime so they never end up in the binary
+	if user, pass := os.Getenv("BASIC_AUTH_USER"), os.Getenv("BASIC_AUTH_PASS"); user != "" || pass != "" {
+		handler = basicAuthHandler(user, pass, handler)
+		log.Println("Basic auth is enabled")
+	}
+	if accessLog {
+		handler = accessLogHandler(handler)
+	}
+{{- if .Metrics}}
+	handler = metricsHandler(handler)
+{{- end}}
+
+	// Create HTTP server with handler and address. TLSNextProto is left nil so
+	// ListenAndServeTLS negotiates HTTP/2 with clients that support it.
+	server := &http.Server{
+		Addr:              net.JoinHostPort(host, port),
+		Handler:           handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+
+	// Start HTTP server with graceful shutdown
+	return startHTTPServer(server, ln)
+}
+
+`
+
+// frontendFramework describes how a frontend is built and where its output lands
+type frontendFramework struct {
+	Name     string // --frontend-framework value, set once resolved
+	Script   string // package.json script that produces the static build
+	OutDir   string // output directory relative to the frontend path
+	AssetDir string // directory of bundled JS and CSS within the output
+	CacheDir string // generated build state relative to the frontend path, if outside node_modules
+}
+
+// Supported frontend frameworks keyed by the --frontend-framework value
+var frontendFrameworks = map[string]frontendFramework{
+	"nextjs":    {Script: "build", OutDir: "out", AssetDir: "_next/static", CacheDir: ".next"},
+	"vite":      {Script: "build", OutDir: "dist", AssetDir: "assets"},
+	"cra":       {Script: "build", OutDir: "build", AssetDir: "static"},
+	"sveltekit": {Script: "build", OutDir: "build", AssetDir: "_app/immutable", CacheDir: ".svelte-kit"},
+}
+
+// Lockfiles used to detect the frontend package manager, checked in order
+var packageManagerLockfiles = []struct {
+	Lockfile string
+	Manager  string
+	Install  []string // clean install from the lockfile
+}{
+	{Lockfile: "pnpm-lock.yaml", Manager: "pnpm", Install: []string{"install", "--frozen-lockfile"}},
+	{Lockfile: "yarn.lock", Manager: "yarn", Install: []string{"install", "--frozen-lockfile"}},
+	{Lockfile: "package-lock.json", Manager: "npm", Install: []string{"ci"}},
+}
+
+// Frameworks returns the names of the supported frontend frameworks in sorted order
+func Frameworks() []string {
+	names := make([]string, 0, len(frontendFrameworks))
+	for name := range frontendFrameworks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// State of a single build: its options, logger and cleanup functions
+type builder struct {
+	options BuildOptions
+	logger  *Logger
+
+	cleanupMu    sync.Mutex
+	cleanupFuncs []func()
+}
+
+func newBuilder(opts BuildOptions) *builder {
+	return &builder{options: opts, logger: NewLogger(opts.Verbose, opts.Quiet)}
+}
+
+// Build bundles the frontend and backend described by opts into a single
+// binary. Cancelling ctx stops the running build steps, removes temp files
+// and returns an error wrapping its cause.
+func Build(ctx context.Context, opts BuildOptions) error {
+	err := newBuilder(opts).build(ctx)
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("build interrupted: %w", context.Cause(ctx))
+	}
+	return err
+}
+
+func (b *builder) build(ctx context.Context) error {
+	start := time.Now()
+	// Remove temp files on success, failure, panic and cancellation
+	defer b.runCleanup()
+
+	backendPath := b.options.BackendPath
+	frontendPath := b.options.FrontendPath // empty when --frontend mounts are given
+	outputDir, err := b.prepareOutputDir(b.options.OutputDir)
+	if err != nil {
+		return fmt.Errorf("invalid output directory: %w", err)
+	}
+
+	// Add correct file extension based on the platform
+	goos := goEnvOrHost(b.options.GOOS, runtime.GOOS)
+	outputBinary, err := outputBinaryPath(outputDir, b.options.BinaryName, goos)
+	if err != nil {
+		return fmt.Errorf("invalid binary name: %w", err)
+	}
+
+	if b.options.NoBackend {
+		b.debugf("Static-only mode: no backend")
+	} else if b.options.BackendBinary == "" {
+		b.debugf("Backend path: %s", backendPath)
+	}
+	if frontendPath != "" {
+		b.debugf("Frontend path: %s", frontendPath)
+	}
+
+	if _, err := regexp.Compile(b.options.ImmutablePattern); err != nil {
+		return fmt.Errorf("invalid --immutable-pattern: %w", err)
+	}
+	if _, err := b.frontendBuildEnv(); err != nil {
+		return fmt.Errorf("invalid frontend build environment: %w", err)
+	}
+	if _, err := splitCommand(b.options.BuildArgs); err != nil {
+		return fmt.Errorf("invalid --build-args: %w", err)
+	}
+	for _, hook := range append(slices.Clone(b.options.PreBuildCommands), b.options.PostBuildCommands...) {
+		if _, err := splitNonEmptyCommand(hook); err != nil {
+			return fmt.Errorf("invalid build hook %q: %w", hook, err)
+		}
+	}
+	if b.options.InstallCommand != "" {
+		if _, err := splitNonEmptyCommand(b.options.InstallCommand); err != nil {
+			return fmt.Errorf("invalid --frontend-install-cmd %q: %w", b.options.InstallCommand, err)
+		}
+	}
+	if b.options.InstallDir != "" {
+		if info, err := os.Stat(b.options.InstallDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("invalid --frontend-install-dir: %s is not a directory", b.options.InstallDir)
+		}
+	}
+	if b.options.ModulePath == "" || strings.ContainsAny(b.options.ModulePath, " \t\r\n\"'`") {
+		return fmt.Errorf("invalid --module %q: expected a module path like example.com/app", b.options.ModulePath)
+	}
+	if err := checkSourceDateEpoch(); err != nil {
+		return fmt.Errorf("invalid build date: %w", err)
+	}
+	if b.options.BuildRetries < 0 {
+		return errors.New("--build-retries must not be negative")
+	}
+	if b.options.DockerTag != "" {
+		b.options.Docker = true
+	}
+	if b.options.Docker && goos != "linux" {
+		return fmt.Errorf("--docker needs a linux bundle, but the target is %s (set --goos linux)", goos)
+	}
+	if b.options.Docker && b.options.BackendCommand != "" {
+		return errors.New("--docker can't be used with --backend-cmd, since the image only contains the bundle and its backend binaries")
+	}
+	for _, pattern := range b.options.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --exclude pattern %q: %w", pattern, err)
+		}
+	}
+	if (b.options.TLSCert == "") != (b.options.TLSKey == "") {
+		return errors.New("--tls-cert and --tls-key must be set together")
+	}
+	if _, err := b.loadMainTemplate(); err != nil {
+		return fmt.Errorf("invalid --template: %w", err)
+	}
+	if b.options.HealthzPath != "" && b.routeCollides(b.options.HealthzPath) {
+		return fmt.Errorf("--healthz-path %q collides with another route", b.options.HealthzPath)
+	}
+	if entry := b.spaEntryName(); entry == "." || entry == ".." || strings.HasPrefix(entry, "../") || path.Ext(entry) == "" {
+		return fmt.Errorf("invalid --spa-entry %q: expected a file in the frontend output, like index.html", b.options.SPAEntry)
+	}
+	if b.options.Manifest {
+		if p := normalizeURLPath(b.options.ManifestPath); b.routeCollides(p) || p == normalizeURLPath(b.options.HealthzPath) || (b.options.Metrics && p == "/metrics") {
+			return fmt.Errorf("--manifest-path %q collides with another route", b.options.ManifestPath)
+		}
+	}
+	if b.options.HealthzCacheTTL < 0 {
+		return errors.New("--healthz-cache-ttl must not be negative")
+	}
+	if b.options.Metrics && (normalizeURLPath(b.options.HealthzPath) == "/metrics" || (!b.options.NoBackend && normalizeAPIPrefix(b.options.APIPrefix) == "/metrics")) {
+		return errors.New("--metrics serves /metrics, which collides with another route")
+	}
+
+	// Fail fast on paths that don't contain buildable projects
+	frontends, err := b.resolveFrontends(frontendPath)
+	if err != nil {
+		return err
+	}
+	var backends []backendSpec
+	if !b.options.NoBackend {
+		if backends, err = b.resolveBackends(backendPath, goos); err != nil {
+			return err
+		}
+	}
+
+	b.debugf("Output binary: %s", outputBinary)
+	if b.options.GOOS != "" || b.options.GOARCH != "" {
+		b.debugf("Target platform: %s/%s", goos, goEnvOrHost(b.options.GOARCH, runtime.GOARCH))
+	}
+
+	var tempDir string
+	if b.options.DryRun {
+		tempDir = filepath.Join(os.TempDir(), "gonext-dry-run")
+		b.infof("[dry-run] would create temp directory: %s", tempDir)
+	} else {
+		tempDir, err = os.MkdirTemp("", "gonext-")
+		if err != nil {
+			return fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		if b.options.KeepTemp {
+			b.addCleanup(func() { b.infof("Kept temp directory: %s", tempDir) })
+		} else {
+			b.addCleanup(func() { os.RemoveAll(tempDir) })
+		}
+		b.debugf("Created temp directory: %s", tempDir)
+	}
+
+	// Bound every build step by --timeout
+	if b.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, b.options.Timeout, fmt.Errorf("build timed out after %s", b.options.Timeout))
+		defer cancel()
+	}
+
+	hooks := b.hookEnv(hookPaths{
+		Backend:   backendPath,
+		Frontend:  primaryFrontend(frontends).Path,
+		OutputDir: outputDir,
+		Binary:    outputBinary,
+		TempDir:   tempDir,
+	})
+	// Pre-build hooks may generate sources, so they run before the frontend cache is checked
+	if err := b.runHooks(ctx, "pre-build", b.options.PreBuildCommands, hooks); err != nil {
+		return err
+	}
+
+	// The frontend and backend builds are independent, so run them concurrently
+	// and report the failures of both
+	backendsDir := filepath.Join(tempDir, "backends")
+	var frontendErr, backendErr error
+	var builds errgroup.Group
+	builds.Go(func() error {
+		if b.options.PrebuiltFrontend {
+			b.infof("Using the prebuilt frontend, skipping its build")
+			return nil
+		}
+		for i, frontend := range frontends {
+			var hash string
+			if !b.options.NoCache && !b.options.DryRun {
+				var err error
+				if hash, err = b.frontendHash(frontend); err != nil {
+					b.infof("Not caching frontend %s: %v", frontend.Dir, err)
+				} else if cached := b.cachedFrontend(hash); cached != "" {
+					b.infof("Frontend %s is unchanged, reusing the cached build", frontend.Dir)
+					b.debugf("Cached build: %s", cached)
+					frontends[i].cachedOutput = cached
+					continue
+				}
+			}
+
+			frontendErr = b.retryBuild(ctx, "Frontend install", func() error {
+				return b.installDependencies(ctx, frontend.Path, frontend.pm)
+			})
+			if frontendErr != nil {
+				frontendErr = fmt.Errorf("failed to install frontend %s dependencies: %w", frontend.Dir, frontendErr)
+				return frontendErr
+			}
+			frontendErr = b.retryBuild(ctx, "Frontend build", func() error {
+				return b.buildNextJS(ctx, frontend.Path, frontend.pm, frontend.framework)
+			})
+			if frontendErr != nil {
+				frontendErr = fmt.Errorf("failed to build frontend %s: %w", frontend.Dir, frontendErr)
+				return frontendErr
+			}
+			if hash != "" {
+				if err := b.storeFrontendCache(frontend, hash); err != nil {
+					b.infof("Failed to cache frontend %s: %v", frontend.Dir, err)
+				}
+			}
+		}
+		b.logDone("Frontend built successfully")
+		return nil
+	})
+	builds.Go(func() error {
+		backendErr = b.buildBackends(ctx, backends, backendsDir)
+		return backendErr
+	})
+	builds.Wait()
+	if err := errors.Join(frontendErr, backendErr); err != nil {
+		return err
+	}
+
+	// Copy only the built frontends (e.g. frontend/out)
+	var assets copyStats
+	var assetTime time.Time
+	if b.options.Reproducible || os.Getenv("SOURCE_DATE_EPOCH") != "" {
+		assetTime = b.buildTimestamp()
+	}
+	for _, frontend := range frontends {
+		fullFrontendPath := b.frontendOutputPath(frontend.Path, frontend.framework)
+		if frontend.cachedOutput != "" {
+			fullFrontendPath = frontend.cachedOutput
+		}
+		destFrontendPath := filepath.Join(tempDir, frontend.Dir)
+		if b.options.DryRun {
+			b.infof("[dry-run] would copy %s to %s", fullFrontendPath, destFrontendPath)
+			continue
+		}
+		if info, err := os.Stat(fullFrontendPath); err != nil || !info.IsDir() {
+			return fmt.Errorf("built frontend directory not found: %s (set --out-dir if your build writes elsewhere)", fullFrontendPath)
+		}
+		stats, err := b.copyDir(fullFrontendPath, destFrontendPath, copyOptions{
+			CompressAssets:  b.options.CompressAssets,
+			StripSourceMaps: b.options.StripSourceMaps,
+			MinifyHTML:      b.options.MinifyHTML,
+			Exclude:         b.options.Exclude,
+			ModTime:         assetTime,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to copy built frontend files: %w", err)
+		}
+		if err := checkIndexFile(destFrontendPath, b.spaEntryName()); err != nil {
+			return fmt.Errorf("built frontend %s has no index.html or %s; check the framework's static export settings, --out-dir, --spa-entry and --exclude", fullFrontendPath, b.spaEntryName())
+		}
+		assets.Files += stats.Files
+		assets.Bytes += stats.Bytes
+		if stats.ExcludedFiles > 0 {
+			b.infof("Excluded %d files (%s) from %s", stats.ExcludedFiles, FormatBytes(stats.ExcludedBytes), frontend.Dir)
+		}
+	}
+	if !b.options.DryRun {
+		b.infof("Frontend files copied successfully")
+	}
+
+	// The backends are embedded into the bundle unless they ship alongside it
+	if b.options.ExternalBackend {
+		for _, backend := range backends {
+			if len(backend.Command) > 0 {
+				continue
+			}
+			builtBackendBinary := filepath.Join(backendsDir, backend.Binary)
+			outputBackend := filepath.Join(outputDir, backend.Binary)
+			if b.options.DryRun {
+				b.infof("[dry-run] would copy %s to %s", builtBackendBinary, outputBackend)
+			} else {
+				if err := copyFile(builtBackendBinary, outputBackend); err != nil {
+					return fmt.Errorf("failed to copy backend binary to output: %w", err)
+				}
+				b.infof("Backend binary copied to: %s", outputBackend)
+			}
+		}
+	}
+
+	// Generate main.go
+	mainFile := filepath.Join(tempDir, "main.go")
+	data := b.templateDataFromOptions(primaryFrontend(frontends).Dir)
+	data.Frontends = frontends
+	data.Backends = backends
+	data.EmbedBackend = !b.options.NoBackend && !b.options.ExternalBackend && b.options.BackendCommand == ""
+	if !b.options.DryRun {
+		info, err := b.newBundleInfo(tempDir, data)
+		if err == nil {
+			data.BundleInfo, err = encodeBundleInfo(info)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to collect bundle information: %w", err)
+		}
+	}
+	if b.options.DryRun {
+		b.infof("[dry-run] would generate %s with embed path %q", mainFile, data.EmbedPath)
+	} else {
+		if err := b.generateMain(mainFile, data); err != nil {
+			return fmt.Errorf("failed to generate main.go: %w", err)
+		}
+		b.infof("main.go generated successfully")
+	}
+
+	// Initialize Go module
+	if err := b.initGoModule(ctx, tempDir); err != nil {
+		return fmt.Errorf("failed to initialize Go module: %w", err)
+	}
+
+	// Catch template substitution problems before the final build
+	if err := b.validateMain(ctx, tempDir); err != nil {
+		return fmt.Errorf("invalid generated main.go: %w", err)
+	}
+
+	// Build the final binary
+	ldflags := strings.TrimSpace(b.metadataLDFlags(data.BuildTime) + " " + b.options.LDFlags)
+	if b.options.Strip {
+		ldflags = "-s -w " + ldflags
+	}
+	if err := b.buildBinary(ctx, tempDir, outputBinary, ldflags); err != nil {
+		return fmt.Errorf("failed to build: %w", err)
+	}
+	if b.options.DryRun {
+		b.infof("[dry-run] bundled binary would be written to: %s", outputBinary)
+	} else {
+		b.infof("Successfully created bundled binary: %s", outputBinary)
+		if info, err := os.Stat(outputBinary); err == nil {
+			b.infof("Build summary: %s, %d embedded files (%s uncompressed), binary size %s",
+				time.Since(start).Round(100*time.Millisecond), assets.Files, FormatBytes(assets.Bytes), FormatBytes(info.Size()))
+		}
+	}
+
+	if b.options.Systemd {
+		if err := b.writeSystemdUnit(outputBinary); err != nil {
+			return fmt.Errorf("failed to write systemd unit: %w", err)
+		}
+	}
+	if b.options.Docker {
+		if err := b.buildDockerImage(ctx, outputDir, filepath.Base(outputBinary), backends); err != nil {
+			return fmt.Errorf("failed to build Docker image: %w", err)
+		}
+	}
+	return b.runHooks(ctx, "post-build", b.options.PostBuildCommands, hooks)
+}
+
+// Resolves the frontend framework and package manager from the options and
+// checks that frontendPath can be built with them
+func (b *builder) resolveFrontend(frontendPath string) (frontendFramework, string, error) {
+	name := b.options.Framework
+	if name == "" {
+		detected, err := detectFramework(frontendPath)
+		if err != nil {
+			return frontendFramework{}, "", fmt.Errorf("failed to detect the frontend framework: %w (supported: %s)", err, strings.Join(Frameworks(), ", "))
+		}
+		if detected == "" {
+			// Keep the former default for projects without signs of a framework
+			detected = "nextjs"
+			b.infof("No frontend framework detected in %s, assuming nextjs (set --frontend-framework to choose)", frontendPath)
+		} else {
+			b.infof("Detected frontend framework: %s", detected)
+		}
+		name = detected
+	}
+	framework, ok := frontendFrameworks[name]
+	if !ok {
+		return frontendFramework{}, "", fmt.Errorf("unsupported frontend framework %q (supported: %s)", name, strings.Join(Frameworks(), ", "))
+	}
+	framework.Name = name
+	b.debugf("Frontend framework: %s", name)
+	if b.options.BuildScript != "" {
+		framework.Script = b.options.BuildScript
+	}
+
+	if b.options.PrebuiltFrontend {
+		// Only the build output is needed, not a buildable project
+		outputPath := b.frontendOutputPath(frontendPath, framework)
+		if info, err := os.Stat(outputPath); err != nil || !info.IsDir() {
+			return frontendFramework{}, "", fmt.Errorf("--prebuilt-frontend is set, but the built frontend %s does not exist (build it first or set --out-dir)", outputPath)
+		}
+		return framework, "", nil
+	}
+
+	if err := checkFrontend(frontendPath, framework.Script); err != nil {
+		return frontendFramework{}, "", fmt.Errorf("invalid frontend: %w", err)
+	}
+
+	pm := b.options.PackageManager
+	if pm == "" {
+		pm = detectPackageManager(b.installDir(frontendPath))
+	} else if !isSupportedPackageManager(pm) {
+		return frontendFramework{}, "", fmt.Errorf("unsupported package manager %q (supported: npm, pnpm, yarn)", pm)
+	}
+	b.debugf("Package manager: %s", pm)
+	return framework, pm, nil
+}
+
+// A frontend app embedded into the bundle
+type frontendSpec struct {
+	Dir      string // directory the build output is copied to, embedded from and served with fs.Sub
+	Prefix   string // URL path prefix it is served under, "" for the site root
+	Path     string // project directory
+	AssetDir string // framework asset directory the bundle warns about when missing
+
+	framework    frontendFramework
+	pm           string
+	cachedOutput string // cached build output reused instead of building
+}
+
+// Resolves the frontends to build: each --frontend mount, or frontendPath
+// served at the site root. Fails on invalid mounts or frontend directories.
+func (b *builder) resolveFrontends(frontendPath string) ([]frontendSpec, error) {
+	var specs []frontendSpec
+	if len(b.options.Frontends) == 0 {
+		dir := filepath.Base(frontendPath)
+		if b.options.EmbedDir != "" {
+			dir = b.options.EmbedDir
+		}
+		specs = append(specs, frontendSpec{Dir: dir, Path: frontendPath})
+	} else if b.options.EmbedDir != "" {
+		return nil, errors.New("--embed-dir only applies to the frontend argument; --frontend mounts are embedded under their names")
+	}
+
+	for _, entry := range b.options.Frontends {
+		name, path, ok := strings.Cut(entry, "=")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("invalid --frontend %q: expected name=path[:prefix]", entry)
+		}
+		prefix := ""
+		if i := strings.LastIndex(path, ":"); i >= 0 && strings.HasPrefix(path[i+1:], "/") {
+			path, prefix = path[:i], normalizeAPIPrefix(path[i+1:])
+		}
+		specs = append(specs, frontendSpec{Dir: name, Prefix: prefix, Path: path})
+	}
+
+	names, prefixes := map[string]bool{}, map[string]bool{}
+	for i, spec := range specs {
+		if err := checkEmbedName(spec.Dir); err != nil {
+			if len(b.options.Frontends) == 0 {
+				return nil, fmt.Errorf("invalid frontend: %w (set --embed-dir to embed it under another name)", err)
+			}
+			return nil, fmt.Errorf("invalid frontend: %w", err)
+		}
+		if names[spec.Dir] || spec.Dir == "backends" {
+			return nil, fmt.Errorf("duplicate frontend name %q", spec.Dir)
+		}
+		if prefixes[spec.Prefix] {
+			return nil, fmt.Errorf("frontend %s is served at %s/, which is already taken by another frontend", spec.Dir, spec.Prefix)
+		}
+		if !b.options.NoBackend && spec.Prefix != "" && spec.Prefix == normalizeAPIPrefix(b.options.APIPrefix) {
+			return nil, fmt.Errorf("frontend %s is served at the API prefix %s", spec.Dir, spec.Prefix)
+		}
+		names[spec.Dir], prefixes[spec.Prefix] = true, true
+
+		framework, pm, err := b.resolveFrontend(spec.Path)
+		if err != nil {
+			return nil, err
+		}
+		specs[i].framework, specs[i].pm = framework, pm
+		specs[i].AssetDir = specs[i].framework.AssetDir
+		b.debugf("Frontend %s: %s at %s/", spec.Dir, spec.Path, spec.Prefix)
+	}
+	return specs, nil
+}
+
+// The frontend served at the site root, or the first one when none is
+func primaryFrontend(specs []frontendSpec) frontendSpec {
+	for _, spec := range specs {
+		if spec.Prefix == "" {
+			return spec
+		}
+	}
+	return specs[0]
+}
+
+// A backend process built into the bundle
+type backendSpec struct {
+	Dir      string   // Go package directory
+	Binary   string   // binary file name, including any platform extension
+	Port     int      // port passed to the process in BACKEND_PORT
+	Prebuilt string   // existing executable used instead of building Dir
+	Command  []string // command the bundle runs instead of a built binary
+}
+
+// Resolves the backends to build: each --backends entry below backendPath,
+// or backendPath itself. Fails on invalid entries or backend directories.
+func (b *builder) resolveBackends(backendPath, goos string) ([]backendSpec, error) {
+	if b.options.BackendCommand != "" {
+		if b.options.BackendBinary != "" || len(b.options.Backends) > 0 {
+			return nil, errors.New("--backend-cmd can't be combined with --backend-binary or --backends")
+		}
+		args, err := splitCommand(b.options.BackendCommand)
+		if err != nil || len(args) == 0 {
+			return nil, fmt.Errorf("invalid --backend-cmd %q: %v", b.options.BackendCommand, err)
+		}
+		b.debugf("Backend command: %s on port %d", strings.Join(args, " "), b.options.BackendPort)
+		return []backendSpec{{Binary: filepath.Base(args[0]), Port: b.options.BackendPort, Command: args}}, nil
+	}
+	if b.options.SkipBackendBuild {
+		return nil, errors.New("--skip-backend-build needs --backend-cmd (use --backend-binary to bundle a prebuilt executable)")
+	}
+
+	var specs []backendSpec
+	if b.options.BackendBinary != "" {
+		if len(b.options.Backends) > 0 {
+			return nil, errors.New("--backend-binary can't be combined with --backends")
+		}
+		if err := checkExecutable(b.options.BackendBinary, goos); err != nil {
+			return nil, fmt.Errorf("invalid --backend-binary: %w", err)
+		}
+	}
+	if len(b.options.Backends) == 0 {
+		if !isPlainFileName(b.options.BackendBinaryName) {
+			return nil, fmt.Errorf("invalid --backend-binary-name %q: must be a plain file name", b.options.BackendBinaryName)
+		}
+		specs = append(specs, backendSpec{
+			Dir:      backendPath,
+			Binary:   addPlatformExtension(b.options.BackendBinaryName, goos),
+			Port:     b.options.BackendPort,
+			Prebuilt: b.options.BackendBinary,
+		})
+	}
+
+	for i, entry := range b.options.Backends {
+		name, portStr, hasPort := strings.Cut(entry, ":")
+		port := b.options.BackendPort + i
+		if hasPort {
+			p, err := strconv.Atoi(portStr)
+			if err != nil || p <= 0 || p > 65535 {
+				return nil, fmt.Errorf("invalid --backends entry %q: bad port %q", entry, portStr)
+			}
+			port = p
+		}
+		binary := filepath.Base(filepath.FromSlash(name))
+		if name == "" || !isPlainFileName(binary) {
+			return nil, fmt.Errorf("invalid --backends entry %q", entry)
+		}
+		specs = append(specs, backendSpec{
+			Dir:    filepath.Join(backendPath, filepath.FromSlash(name)),
+			Binary: addPlatformExtension(binary, goos),
+			Port:   port,
+		})
+	}
+
+	binaries, ports := map[string]bool{}, map[int]bool{}
+	for _, spec := range specs {
+		if binaries[spec.Binary] {
+			return nil, fmt.Errorf("duplicate backend %q", spec.Binary)
+		}
+		if ports[spec.Port] {
+			return nil, fmt.Errorf("backend %s uses port %d, which is already taken by another backend", spec.Binary, spec.Port)
+		}
+		binaries[spec.Binary], ports[spec.Port] = true, true
+
+		if spec.Prebuilt != "" {
+			b.debugf("Backend %s: prebuilt %s on port %d", spec.Binary, spec.Prebuilt, spec.Port)
+			continue
+		}
+		if err := checkBackend(spec.Dir); err != nil {
+			return nil, fmt.Errorf("invalid backend: %w", err)
+		}
+		b.debugf("Backend %s: %s on port %d", spec.Binary, spec.Dir, spec.Port)
+	}
+	return specs, nil
+}
+
+// Splits a command line into arguments on whitespace, honouring single and
+// double quotes and backslash escapes
+func splitCommand(line string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// Splits a command line like splitCommand, failing when it has no arguments
+func splitNonEmptyCommand(line string) ([]string, error) {
+	args, err := splitCommand(line)
+	if err == nil && len(args) == 0 {
+		err = errors.New("empty command")
+	}
+	return args, err
+}
+
+// Reports whether name is a single path element other than . and ..
+func isPlainFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && name == filepath.Base(name)
+}
+
+// Returns the directory the frontend build writes to, honoring --out-dir
+func (b *builder) frontendOutputPath(frontendPath string, framework frontendFramework) string {
+	if b.options.OutDir != "" {
+		return filepath.Join(frontendPath, b.options.OutDir)
+	}
+	return filepath.Join(frontendPath, framework.OutDir)
+}
+
+// Runs an external command, or only logs it in --dry-run mode
+func (b *builder) runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	dir := cmd.Dir
+	if dir == "" {
+		dir = "."
+	}
+	if b.options.DryRun {
+		b.infof("[dry-run] would run in %s: %s", dir, strings.Join(cmd.Args, " "))
+		return nil
+	}
+	b.debugf("Running in %s: %s", dir, strings.Join(cmd.Args, " "))
+	err := runProcessGroup(cmd)
+	if err != nil && ctx.Err() != nil {
+		// Report the timeout rather than the "signal: killed" it caused
+		return fmt.Errorf("%w (%s)", context.Cause(ctx), err)
+	}
+	return err
+}
+
+// Logs the success of a step, skipped in --dry-run mode where nothing ran
+func (b *builder) logDone(format string, v ...any) {
+	if !b.options.DryRun {
+		b.infof(format, v...)
+	}
+}
+
+// Resolves outputDir to an absolute path, since the bundle is built from a
+// temp directory, and creates it unless it already exists as a directory
+func (b *builder) prepareOutputDir(outputDir string) (string, error) {
+	abs, err := filepath.Abs(outputDir)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(abs); err == nil {
+		if !info.IsDir() {
+			return "", fmt.Errorf("%s exists and is not a directory", outputDir)
+		}
+		return abs, nil
+	}
+	if b.options.DryRun {
+		b.infof("[dry-run] would create %s", abs)
+		return abs, nil
+	}
+	if err := os.MkdirAll(abs, 0755); err != nil {
+		return "", err
+	}
+	b.debugf("Created output directory %s", abs)
+	return abs, nil
+}
+
+// Joins the binary name onto outputDir with the target platform's extension.
+// The name must be a bare file name on every platform, so the binary can't
+// be written outside outputDir.
+func outputBinaryPath(outputDir, name, goos string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\:\x00") {
+		return "", fmt.Errorf("%q must be a file name without path separators", name)
+	}
+
+	binary := addPlatformExtension(filepath.Join(outputDir, name), goos)
+	rel, err := filepath.Rel(outputDir, binary)
+	if err != nil || rel != filepath.Base(binary) {
+		return "", fmt.Errorf("%q would be written outside %s", name, outputDir)
+	}
+	return binary, nil
+}
+
+// Adds the correct file extension based on the target platform, unless already present
+func addPlatformExtension(binary, goos string) string {
+	if goos == "windows" && !strings.EqualFold(filepath.Ext(binary), ".exe") {
+		return binary + ".exe"
+	}
+	return binary
+}
+
+// Returns the directory dependencies are installed in: --frontend-install-dir,
+// e.g. a monorepo's workspace root, or else the frontend itself. Its
+// package.json, lockfile and node_modules decide whether and how to install.
+func (b *builder) installDir(frontendPath string) string {
+	if b.options.InstallDir != "" {
+		return b.options.InstallDir
+	}
+	return frontendPath
+}
+
+// Detects the package manager from the lockfile in frontendPath, falling back to npm
+func detectPackageManager(frontendPath string) string {
+	for _, candidate := range packageManagerLockfiles {
+		if _, err := os.Stat(filepath.Join(frontendPath, candidate.Lockfile)); err == nil {
+			return candidate.Manager
+		}
+	}
+	return "npm"
+}
+
+func isSupportedPackageManager(pm string) bool {
+	for _, candidate := range packageManagerLockfiles {
+		if candidate.Manager == pm {
+			return true
+		}
+	}
+	return false
+}
+
+// Installs the frontend's dependencies when node_modules is missing from the
+// install directory, with --frontend-install-cmd or else a clean install when
+// pm's lockfile is present, unless --no-install is set
+func (b *builder) installDependencies(ctx context.Context, frontendPath, pm string) error {
+	dir := b.installDir(frontendPath)
+	if b.options.NoInstall || !hasDependencies(dir) {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, "node_modules")); err == nil {
+		return nil
+	}
+
+	name, args := pm, []string{"install"}
+	if b.options.InstallCommand != "" {
+		command, err := splitNonEmptyCommand(b.options.InstallCommand)
+		if err != nil {
+			return fmt.Errorf("invalid --frontend-install-cmd %q: %w", b.options.InstallCommand, err)
+		}
+		name, args = command[0], command[1:]
+	} else {
+		for _, candidate := range packageManagerLockfiles {
+			if candidate.Manager != pm {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(dir, candidate.Lockfile)); err == nil {
+				args = candidate.Install
+			}
+		}
+	}
+
+	b.infof("node_modules is missing, installing frontend dependencies with %s...", strings.Join(append([]string{name}, args...), " "))
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	env, err := b.frontendBuildEnv()
+	if err != nil {
+		return err
+	}
+	cmd.Env = env
+	stdout, stderr := b.stageOutput("frontend")
+	defer stdout.Flush()
+	defer stderr.Flush()
+	// As with the build, stderr is only streamed with --verbose
+	var captured stderrCapture
+	cmd.Stdout, cmd.Stderr = stdout, &captured
+	if b.logger.level <= levelDebug {
+		cmd.Stderr = io.MultiWriter(stderr, &captured)
+	}
+	if err := b.runCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("%w%s", err, captured.Summary())
+	}
+	return nil
+}
+
+// Longest wait between build retries
+const maxRetryBackoff = 30 * time.Second
+
+// Runs build, retrying failures up to --build-retries times with exponential
+// backoff. Cancellation (e.g. --timeout) is not retried.
+func (b *builder) retryBuild(ctx context.Context, what string, build func() error) error {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		err := build()
+		if err == nil || attempt > b.options.BuildRetries || ctx.Err() != nil {
+			return err
+		}
+		b.infof("%s failed (attempt %d of %d), retrying in %s: %v", what, attempt, b.options.BuildRetries+1, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+func (b *builder) buildNextJS(ctx context.Context, frontendPath, pm string, framework frontendFramework) error {
+	b.infof("Building frontend with %s...", pm)
+	args, err := b.frontendBuildArgs(pm, framework.Script)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, pm, args...)
+	cmd.Dir = frontendPath
+	env, err := b.frontendBuildEnv()
+	if err != nil {
+		return err
+	}
+	cmd.Env = env
+	stdout, stderr := b.stageOutput("frontend")
+	defer stdout.Flush()
+	defer stderr.Flush()
+	// stderr is captured for the error and only streamed with --verbose
+	var captured stderrCapture
+	cmd.Stdout, cmd.Stderr = stdout, &captured
+	if b.logger.level <= levelDebug {
+		cmd.Stderr = io.MultiWriter(stderr, &captured)
+	}
+	if err := b.runCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("%w%s", err, captured.Summary())
+	}
+	return nil
+}
+
+// Returns the package manager arguments that run script with --build-args.
+// npm only forwards arguments that come after "--".
+func (b *builder) frontendBuildArgs(pm, script string) ([]string, error) {
+	extra, err := splitCommand(b.options.BuildArgs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --build-args: %w", err)
+	}
+	args := []string{"run", script}
+	if len(extra) > 0 && pm == "npm" {
+		args = append(args, "--")
+	}
+	return append(args, extra...), nil
+}
+
+func goEnvOrHost(value, host string) string {
+	if value == "" {
+		return host
+	}
+	return value
+}
+
+// Returns the environment for go build, applying the --goos/--goarch targets.
+// Docker bundles are linked statically so they run on images without libc.
+func (b *builder) goBuildEnv() []string {
+	env := os.Environ()
+	if b.options.Docker {
+		env = append(env, "CGO_ENABLED=0")
+	}
+	if b.options.GOOS != "" {
+		env = append(env, "GOOS="+b.options.GOOS)
+	}
+	if b.options.GOARCH != "" {
+		env = append(env, "GOARCH="+b.options.GOARCH)
+	}
+	return env
+}
+
+// Reports whether the bundle and its backends are built with -trimpath
+func (b *builder) trimPath() bool {
+	return b.options.TrimPath || b.options.Reproducible
+}
+
+// Returns the go build arguments for an output binary with optional ldflags and tags.
+// ldflags stay a single argument so go itself splits them, respecting quotes.
+// trimpath keeps the temp directory and the machine's paths out of the binary.
+func goBuildArgs(outputBinary, ldflags, tags string, trimpath bool) []string {
+	args := []string{"build", "-o", outputBinary}
+	if trimpath {
+		args = append(args, "-trimpath")
+	}
+	if ldflags != "" {
+		args = append(args, "-ldflags="+ldflags)
+	}
+	if tags = strings.Join(strings.Fields(strings.ReplaceAll(tags, ",", " ")), ","); tags != "" {
+		args = append(args, "-tags="+tags)
+	}
+	return args
+}
+
+// Builds each backend into dir
+func (b *builder) buildBackends(ctx context.Context, backends []backendSpec, dir string) error {
+	if len(backends) > 0 && !b.options.DryRun {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create backends directory: %w", err)
+		}
+	}
+	for _, backend := range backends {
+		if len(backend.Command) > 0 {
+			continue
+		}
+		if backend.Prebuilt != "" {
+			if b.options.DryRun {
+				b.infof("[dry-run] would copy %s to %s", backend.Prebuilt, filepath.Join(dir, backend.Binary))
+				continue
+			}
+			if err := copyFile(backend.Prebuilt, filepath.Join(dir, backend.Binary)); err != nil {
+				return fmt.Errorf("failed to copy backend binary %s: %w", backend.Prebuilt, err)
+			}
+			b.infof("Using the prebuilt backend %s", backend.Prebuilt)
+			continue
+		}
+		if err := b.buildGoBackend(ctx, backend.Dir, filepath.Join(dir, backend.Binary)); err != nil {
+			return fmt.Errorf("failed to build backend %s: %w", backend.Dir, err)
+		}
+		b.logDone("Go backend %s built successfully", backend.Binary)
+	}
+	return nil
+}
+
+func (b *builder) buildGoBackend(ctx context.Context, backendPath, outputBinary string) error {
+	b.infof("Building Go backend...")
+	cmd := exec.CommandContext(ctx, "go", goBuildArgs(outputBinary, b.options.BackendLDFlags, b.options.BackendTags, b.trimPath())...)
+	cmd.Dir = backendPath
+	cmd.Env = b.goBuildEnv()
+	stdout, stderr := b.stageOutput("backend")
+	defer stdout.Flush()
+	defer stderr.Flush()
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	return b.runCommand(ctx, cmd)
+}
+
+// copyOptions controls how copyDir writes the frontend assets
+type copyOptions struct {
+	CompressAssets  bool      // store compressible files gzipped as <name>.gz
+	StripSourceMaps bool      // skip *.map files
+	MinifyHTML      bool      // minify .html files
+	Exclude         []string  // glob patterns of files and directories to skip
+	ModTime         time.Time // when set, the mod-time given to every copied file
+}
+
+// copyStats counts what copyDir copied and excluded, by source size
+type copyStats struct {
+	Files         int
+	Bytes         int64
+	ExcludedFiles int
+	ExcludedBytes int64
+}
+
+// Reports whether the slash-separated relative path matches an --exclude
+// pattern. Patterns without a slash also match any base name, and a trailing
+// slash only matches directories.
+func excluded(patterns []string, relPath string, isDir bool) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/") {
+			if !isDir {
+				continue
+			}
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+		name := relPath
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(relPath)
+		}
+		if ok, _ := path.Match(strings.TrimPrefix(pattern, "/"), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Sums the files and bytes below dir
+func dirUsage(dir string) (int, int64) {
+	var files int
+	var size int64
+	filepath.Walk(dir, func(_ string, info fs.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files++
+			size += info.Size()
+		}
+		return nil
+	})
+	return files, size
+}
+
+// Extensions of formats that are already compressed and gain nothing from gzip
+var precompressedExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true, ".avif": true,
+	".woff": true, ".woff2": true, ".gz": true, ".br": true, ".zip": true,
+	".mp3": true, ".mp4": true, ".webm": true, ".ogg": true,
+}
+
+// Number of files copyDir copies concurrently
+var copyWorkers = runtime.NumCPU()
+
+// Copies src into dst, creating directories while walking the tree and
+// streaming files to dst from a bounded pool of workers. filepath.Walk visits
+// entries in lexical order, so the result doesn't depend on directory order.
+func (b *builder) copyDir(src, dst string, opts copyOptions) (copyStats, error) {
+	var stats copyStats
+	type copyJob struct{ src, dst string }
+	jobs := make(chan copyJob)
+
+	var (
+		mu      sync.Mutex
+		copyErr error
+	)
+	failed := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return copyErr
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < max(copyWorkers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				// Drain the remaining jobs without copying once a file fails
+				if failed() != nil {
+					continue
+				}
+				if err := copyAsset(job.src, job.dst, opts); err != nil {
+					mu.Lock()
+					if copyErr == nil {
+						copyErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	walkErr := filepath.Walk(src, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := failed(); err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		dstPath := filepath.Join(dst, relPath)
+
+		if relPath != "." && excluded(opts.Exclude, filepath.ToSlash(relPath), info.IsDir()) {
+			b.debugf("Excluding %s", relPath)
+			if info.IsDir() {
+				files, size := dirUsage(path)
+				stats.ExcludedFiles += files
+				stats.ExcludedBytes += size
+				return filepath.SkipDir
+			}
+			stats.ExcludedFiles++
+			stats.ExcludedBytes += info.Size()
+			return nil
+		}
+		if info.IsDir() {
+			return os.MkdirAll(dstPath, info.Mode())
+		}
+		if opts.StripSourceMaps && strings.EqualFold(filepath.Ext(path), ".map") {
+			b.debugf("Skipping source map %s", relPath)
+			return nil
+		}
+
+		stats.Files++
+		stats.Bytes += info.Size()
+
+		jobs <- copyJob{src: path, dst: dstPath}
+		return nil
+	})
+	close(jobs)
+	wg.Wait()
+
+	if walkErr != nil {
+		return stats, walkErr
+	}
+	if err := failed(); err != nil {
+		return stats, err
+	}
+	if !opts.ModTime.IsZero() {
+		return stats, setModTimes(dst, opts.ModTime)
+	}
+	return stats, nil
+}
+
+// Sets the access and modification times of everything below dir to t
+func setModTimes(dir string, t time.Time) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Chtimes(path, t, t)
+	})
+}
+
+// Copies a single frontend file, gzipping it when opts asks for it and it helps
+func copyAsset(src, dst string, opts copyOptions) error {
+	if ext := strings.ToLower(filepath.Ext(src)); opts.MinifyHTML && (ext == ".html" || ext == ".htm") {
+		if err := minifyHTMLFile(src, dst); err != nil {
+			return err
+		}
+		if !opts.CompressAssets {
+			return nil
+		}
+		// Compress the minified copy in place of the original
+		compressed, err := gzipFile(dst, dst+".gz")
+		if err != nil || !compressed {
+			return err
+		}
+		return os.Remove(dst)
+	}
+	if opts.CompressAssets && !precompressedExtensions[strings.ToLower(filepath.Ext(src))] {
+		compressed, err := gzipFile(src, dst+".gz")
+		if err != nil || compressed {
+			return err
+		}
+	}
+	return copyFile(src, dst)
+}
+
+// Gzips src into dst, reporting whether the result is smaller than the source.
+// The compressed file is removed when it isn't.
+func gzipFile(src, dst string) (bool, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return false, err
+	}
+	gz, err := gzip.NewWriterLevel(out, gzip.BestCompression)
+	if err != nil {
+		out.Close()
+		return false, err
+	}
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		return false, err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		return false, err
+	}
+	size, err := out.Seek(0, io.SeekCurrent)
+	if err != nil {
+		out.Close()
+		return false, err
+	}
+	if err := out.Close(); err != nil {
+		return false, err
+	}
+
+	// Keep the original when gzip doesn't make it smaller
+	if size >= info.Size() {
+		return false, os.Remove(dst)
+	}
+	return true, os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// Copies src to dst by streaming, preserving the file mode and modification time
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	// OpenFile only applies the mode when creating, so set it for existing files too
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// Normalizes the API prefix to a leading slash without a trailing one
+func normalizeAPIPrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
+// Reports whether a route of the generated server at p, such as the health
+// endpoint, would take the site root or shadow the proxied API. Routes are
+// relative to --base-path, so one that repeats it is rejected too.
+func (b *builder) routeCollides(p string) bool {
+	p = normalizeAPIPrefix(p)
+	under := func(prefix string) bool {
+		return prefix != "" && (p == prefix || strings.HasPrefix(p, prefix+"/"))
+	}
+	return p == "" || (!b.options.NoBackend && under(normalizeAPIPrefix(b.options.APIPrefix))) || under(normalizeAPIPrefix(b.options.BasePath))
+}
+
+// Ensures a non-empty URL path starts with a slash
+func normalizeURLPath(path string) string {
+	if path == "" || strings.HasPrefix(path, "/") {
+		return path
+	}
+	return "/" + path
+}
+
+// Values substituted into mainTemplate, and available to --template files
+type mainTemplateData struct {
+	EmbedPath   string
+	FrontendDir string
+	APIPrefix   string
+
+	BackendReadyTimeout time.Duration
+	BackendHealthPath   string
+	BackendRestart      bool
+	BackendGracePeriod  time.Duration
+	ReadHeaderTimeout   time.Duration
+	ReadTimeout         time.Duration
+	WriteTimeout        time.Duration
+	IdleTimeout         time.Duration
+	ShutdownTimeout     time.Duration
+	Compression         bool
+	ImmutablePattern    string
+	CompressedAssets    bool
+	TLSCert             string
+	TLSKey              string
+	Port                int // listen port when PORT is unset
+	Host                string
+	CORSOrigins         []string
+	CORSMethods         string
+	CORSHeaders         string
+	SPAFallback         bool
+	StrictRoutes        bool
+	SPAEntry            string
+	TrailingSlash       bool
+	AccessLog           bool
+	HealthzPath         string
+	HealthzCacheTTL     time.Duration
+	Metrics             bool
+	BasePath            string         // prefix stripped from requests, without a trailing slash
+	ManifestPath        string         // empty unless --manifest is set
+	Dotenv              bool           // load a .env file at startup
+	BundleInfo          string         // encoded bundleInfo, read back by inspect
+	Frontends           []frontendSpec // each is embedded from its Dir next to main.go
+	Backend             bool           // whether the bundle launches a backend process
+	Backends            []backendSpec  // binaries are in the backends directory next to main.go
+	EmbedBackend        bool
+	GOOS                string // target operating system of the bundle
+	BuildTime           time.Time
+}
+
+// Port the bundle listens on unless PORT is set
+const defaultPort = 8080
+
+// Builds the template values from the build options
+func (b *builder) templateDataFromOptions(frontendDir string) mainTemplateData {
+	return mainTemplateData{
+		EmbedPath:   frontendDir,
+		FrontendDir: frontendDir,
+		APIPrefix:   normalizeAPIPrefix(b.options.APIPrefix),
+
+		BackendReadyTimeout: b.options.BackendReadyTimeout,
+		BackendHealthPath:   normalizeURLPath(b.options.BackendHealthPath),
+		Backend:             !b.options.NoBackend,
+		BackendRestart:      !b.options.NoRestart,
+		BackendGracePeriod:  b.options.BackendGracePeriod,
+		ReadHeaderTimeout:   b.options.ReadHeaderTimeout,
+		ReadTimeout:         b.options.ReadTimeout,
+		WriteTimeout:        b.options.WriteTimeout,
+		IdleTimeout:         b.options.IdleTimeout,
+		ShutdownTimeout:     b.options.ShutdownTimeout,
+		Compression:         b.options.Compression || b.options.CompressAssets,
+		ImmutablePattern:    b.options.ImmutablePattern,
+		CompressedAssets:    b.options.CompressAssets,
+		TLSCert:             b.options.TLSCert,
+		TLSKey:              b.options.TLSKey,
+		Port:                defaultPort,
+		Host:                b.options.Host,
+		CORSOrigins:         b.options.CORSOrigins,
+		CORSMethods:         strings.Join(b.options.CORSMethods, ", "),
+		CORSHeaders:         strings.Join(b.options.CORSHeaders, ", "),
+		SPAFallback:         b.options.SPAFallback,
+		StrictRoutes:        b.options.StrictRoutes,
+		SPAEntry:            b.spaEntryName(),
+		TrailingSlash:       b.options.TrailingSlash,
+		AccessLog:           b.options.AccessLog,
+		HealthzPath:         normalizeURLPath(b.options.HealthzPath),
+		HealthzCacheTTL:     b.options.HealthzCacheTTL,
+		Metrics:             b.options.Metrics,
+		BasePath:            normalizeAPIPrefix(b.options.BasePath),
+		ManifestPath:        b.templateManifestPath(),
+		Dotenv:              b.options.Dotenv,
+		Frontends:           []frontendSpec{{Dir: frontendDir}},
+		GOOS:                goEnvOrHost(b.options.GOOS, runtime.GOOS),
+		BuildTime:           b.buildTimestamp(),
+	}
+}
+
+// Returns the --spa-entry file as a path relative to the frontend output
+func (b *builder) spaEntryName() string {
+	return path.Clean(strings.TrimPrefix(b.options.SPAEntry, "/"))
+}
+
+// Returns the manifest endpoint path, or "" without --manifest
+func (b *builder) templateManifestPath() string {
+	if !b.options.Manifest {
+		return ""
+	}
+	return normalizeURLPath(b.options.ManifestPath)
+}
+
+// Parses the --template file, or the built-in mainTemplate when none is set
+func (b *builder) loadMainTemplate() (*template.Template, error) {
+	if b.options.TemplatePath == "" {
+		return template.New("main").Parse(mainTemplate)
+	}
+	source, err := os.ReadFile(b.options.TemplatePath)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(b.options.TemplatePath)).Parse(string(source))
+}
+
+func (b *builder) generateMain(filename string, data mainTemplateData) error {
+	// //go:embed patterns always use forward slashes, even on Windows
+	data.EmbedPath = filepath.ToSlash(data.EmbedPath)
+	data.FrontendDir = filepath.ToSlash(data.FrontendDir)
+	data.Frontends = slices.Clone(data.Frontends)
+	for i := range data.Frontends {
+		data.Frontends[i].Dir = filepath.ToSlash(data.Frontends[i].Dir)
+	}
+
+	if err := checkEmbedName(data.EmbedPath); err != nil {
+		return err
+	}
+	for _, frontend := range data.Frontends {
+		if err := checkEmbedName(frontend.Dir); err != nil {
+			return err
+		}
+	}
+
+	tmpl, err := b.loadMainTemplate()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return tmpl.Execute(file, data)
+}
+
+// Version of the Prometheus client required by bundles built with --metrics
+const prometheusClientVersion = "v1.19.1"
+
+// Creates the generated module, pins its go directive and tidies it. Call
+// after generateMain so tidy sees the generated imports.
+func (b *builder) initGoModule(ctx context.Context, dir string) error {
+	b.infof("Initializing Go module...")
+	version := b.options.GoVersion
+	if version == "" {
+		version = toolchainGoVersion()
+	}
+
+	steps := [][]string{{"mod", "init", b.options.ModulePath}}
+	if version != "" {
+		b.debugf("Pinning the generated module to go %s", version)
+		steps = append(steps, []string{"mod", "edit", "-go=" + version})
+	}
+	if b.options.Metrics {
+		steps = append(steps, []string{"get", "github.com/prometheus/client_golang@" + prometheusClientVersion})
+	}
+	steps = append(steps, []string{"mod", "tidy"})
+
+	for _, args := range steps {
+		cmd := exec.CommandContext(ctx, "go", args...)
+		cmd.Dir = dir
+		cmd.Stdout = b.commandStdout()
+		cmd.Stderr = os.Stderr
+		if err := b.runCommand(ctx, cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the version of the installed go toolchain (e.g. "1.22.4"), or ""
+// for development builds and when it can't be determined
+func toolchainGoVersion() string {
+	out, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return ""
+	}
+	version, ok := strings.CutPrefix(strings.TrimSpace(string(out)), "go")
+	if !ok || strings.ContainsAny(version, " -+") {
+		return ""
+	}
+	return version
+}
+
+func (b *builder) buildBinary(ctx context.Context, tempDir, outputBinary, ldflags string) error {
+	b.infof("Building the final binary...")
+	cmd := exec.CommandContext(ctx, "go", goBuildArgs(outputBinary, ldflags, b.options.Tags, b.trimPath())...)
+	cmd.Dir = tempDir
+	cmd.Env = b.goBuildEnv()
+	cmd.Stdout = b.commandStdout()
+	cmd.Stderr = os.Stderr
+	return b.runCommand(ctx, cmd)
+}
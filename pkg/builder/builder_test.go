@@ -0,0 +1,2162 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test that the .exe extension is appended exactly once for Windows targets
+func TestAddPlatformExtension(t *testing.T) {
+	tests := []struct {
+		binary string
+		goos   string
+		want   string
+	}{
+		{binary: "app", goos: "windows", want: "app.exe"},
+		{binary: "app.exe", goos: "windows", want: "app.exe"},
+		{binary: "app.EXE", goos: "windows", want: "app.EXE"},
+		{binary: "app", goos: "linux", want: "app"},
+	}
+
+	for _, tt := range tests {
+		if got := addPlatformExtension(tt.binary, tt.goos); got != tt.want {
+			t.Errorf("addPlatformExtension(%q, %q): expected %q, but got %q", tt.binary, tt.goos, tt.want, got)
+		}
+	}
+}
+
+// Test that binary names can't place the bundle outside the output directory
+func TestOutputBinaryPath(t *testing.T) {
+	outputDir := filepath.Join("build", "dist")
+	tests := []struct {
+		name  string
+		goos  string
+		want  string
+		valid bool
+	}{
+		{name: "app", goos: "linux", want: filepath.Join(outputDir, "app"), valid: true},
+		{name: "app", goos: "windows", want: filepath.Join(outputDir, "app.exe"), valid: true},
+		{name: "my..app", goos: "linux", want: filepath.Join(outputDir, "my..app"), valid: true},
+		{name: "", goos: "linux"},
+		{name: ".", goos: "linux"},
+		{name: "..", goos: "linux"},
+		{name: "../app", goos: "linux"},
+		{name: "../../etc/foo", goos: "linux"},
+		{name: "sub/app", goos: "linux"},
+		{name: `..\app`, goos: "windows"},
+		{name: `C:\Windows\app`, goos: "windows"},
+		{name: "C:app", goos: "windows"},
+		{name: "/etc/foo", goos: "linux"},
+	}
+
+	for _, tt := range tests {
+		got, err := outputBinaryPath(outputDir, tt.name, tt.goos)
+		if !tt.valid {
+			if err == nil {
+				t.Errorf("outputBinaryPath(%q): expected an error, but got %q", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("outputBinaryPath(%q): expected %q, but got %q (%v)", tt.name, tt.want, got, err)
+		}
+	}
+}
+
+// Test that a missing output directory is created and a file in its place is rejected
+func TestPrepareOutputDir(t *testing.T) {
+	root := t.TempDir()
+
+	dir, err := newBuilder(DefaultOptions()).prepareOutputDir(filepath.Join(root, "dist", "release"))
+	if err != nil {
+		t.Fatalf("prepareOutputDir: unexpected error: %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() || !filepath.IsAbs(dir) {
+		t.Errorf("Expected %s to be created as an absolute directory path", dir)
+	}
+
+	file := filepath.Join(root, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newBuilder(DefaultOptions()).prepareOutputDir(file); err == nil {
+		t.Errorf("prepareOutputDir: expected an error for a file")
+	}
+}
+
+// Test that invalid option combinations fail before anything is built
+func TestBuildValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		opts func(*BuildOptions)
+		want string
+	}{
+		{name: "docker with backend command", opts: func(o *BuildOptions) {
+			o.Docker, o.GOOS, o.BackendCommand = true, "linux", "uvicorn app:main"
+		}, want: "--docker can't be used with --backend-cmd"},
+		{name: "empty pre-build hook", opts: func(o *BuildOptions) {
+			o.PreBuildCommands = []string{"  "}
+		}, want: `invalid build hook "  ": empty command`},
+		{name: "unterminated post-build hook", opts: func(o *BuildOptions) {
+			o.PostBuildCommands = []string{`echo "done`}
+		}, want: "invalid build hook"},
+		{name: "blank install command", opts: func(o *BuildOptions) {
+			o.InstallCommand = "  "
+		}, want: `invalid --frontend-install-cmd "  ": empty command`},
+		{name: "manifest at the API prefix", opts: func(o *BuildOptions) {
+			o.Manifest, o.ManifestPath = true, "/api"
+		}, want: `--manifest-path "/api" collides`},
+		{name: "manifest under the API prefix", opts: func(o *BuildOptions) {
+			o.Manifest, o.ManifestPath = true, "/api/manifest.json"
+		}, want: `--manifest-path "/api/manifest.json" collides`},
+		{name: "manifest under the base path", opts: func(o *BuildOptions) {
+			o.Manifest, o.ManifestPath, o.BasePath = true, "/app/manifest.json", "/app"
+		}, want: `--manifest-path "/app/manifest.json" collides`},
+		{name: "manifest at the health endpoint", opts: func(o *BuildOptions) {
+			o.Manifest, o.ManifestPath = true, "/healthz"
+		}, want: `--manifest-path "/healthz" collides`},
+		{name: "health endpoint under the API prefix", opts: func(o *BuildOptions) {
+			o.HealthzPath = "/api/health"
+		}, want: `--healthz-path "/api/health" collides`},
+	}
+
+	for _, tt := range tests {
+		opts := DefaultOptions()
+		opts.BackendPath, opts.FrontendPath = t.TempDir(), t.TempDir()
+		opts.OutputDir, opts.BinaryName = t.TempDir(), "app"
+		opts.Quiet = true
+		tt.opts(&opts)
+		err := Build(context.Background(), opts)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Build with %s: expected an error containing %q, but got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+// Test that the Dockerfile publishes the bundle on all interfaces
+func TestDockerfile(t *testing.T) {
+	b := newBuilder(DefaultOptions())
+	b.options.Host = "127.0.0.1"
+	dir := t.TempDir()
+	if err := b.buildDockerImage(context.Background(), dir, "app", nil); err != nil {
+		t.Fatalf("buildDockerImage: unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "Dockerfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"ENV PORT=8080\n", "ENV HOST=0.0.0.0\n", "EXPOSE 8080\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected the Dockerfile to contain %q, but got:\n%s", want, data)
+		}
+	}
+}
+
+// Test that the systemd unit quotes its environment assignments
+func TestSystemdUnit(t *testing.T) {
+	b := newBuilder(DefaultOptions())
+	b.options.Host = `my "host"`
+	dir := t.TempDir()
+	if err := b.writeSystemdUnit(filepath.Join(dir, "app")); err != nil {
+		t.Fatalf("writeSystemdUnit: unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "app.service"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`Environment="PORT=8080"` + "\n", `Environment="HOST=my \"host\""` + "\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected the unit to contain %q, but got:\n%s", want, data)
+		}
+	}
+}
+
+// Test that inspect finds the bundle information among other binary content
+func TestReadBundleInfo(t *testing.T) {
+	want := BundleInfo{
+		Version:  "1.2.3",
+		Backends: []string{"api"},
+		Routes:   BundleRoutes{APIPrefix: "/api", Frontends: []string{"/"}},
+		Files:    []BundleFile{{Path: "out/index.html", Size: 42}},
+	}
+	encoded, err := encodeBundleInfo(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A stray marker, as an embedded file might contain, comes first
+	binary := filepath.Join(t.TempDir(), "app")
+	content := "\x7fELF\x00" + bundleInfoMarker + "not json\x00padding" + encoded + "trailer"
+	if err := os.WriteFile(binary, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadBundleInfo(binary)
+	if err != nil {
+		t.Fatalf("ReadBundleInfo: unexpected error: %v", err)
+	}
+	if got.Version != want.Version || len(got.Files) != 1 || got.Files[0] != want.Files[0] || got.Routes.APIPrefix != "/api" {
+		t.Errorf("ReadBundleInfo: expected %+v, but got %+v", want, got)
+	}
+
+	if err := os.WriteFile(binary, []byte("no info here"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadBundleInfo(binary); err == nil {
+		t.Errorf("ReadBundleInfo: expected an error for a binary without bundle information")
+	}
+}
+
+// Test which frontend directory names can be embedded
+func TestCheckEmbedName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{name: "front-end", valid: true},
+		{name: "my front end", valid: true},
+		{name: "フロントエンド", valid: true},
+		{name: "café (v2)", valid: true},
+		{name: "", valid: false},
+		{name: ".", valid: false},
+		{name: "..", valid: false},
+		{name: "web/app", valid: false},
+		{name: `web\app`, valid: false},
+		{name: "out.", valid: false},
+		{name: `say "hi"`, valid: false},
+		{name: "site*", valid: false},
+		{name: "build[1]", valid: false},
+		{name: "tab\tname", valid: false},
+	}
+
+	for _, tt := range tests {
+		err := checkEmbedName(tt.name)
+		if tt.valid && err != nil {
+			t.Errorf("checkEmbedName(%q): expected no error, but got %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("checkEmbedName(%q): expected an error, but got none", tt.name)
+		}
+	}
+}
+
+// Test that invalid backend options are reported as errors
+func TestResolveBackends(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  func(*BuildOptions)
+		valid bool
+	}{
+		{name: "command", opts: func(o *BuildOptions) { o.BackendCommand = "uvicorn app:main" }, valid: true},
+		{name: "unterminated command", opts: func(o *BuildOptions) { o.BackendCommand = `node "app` }},
+		{name: "command with binary", opts: func(o *BuildOptions) { o.BackendCommand, o.BackendBinary = "serve", "backend" }},
+		{name: "skip without command", opts: func(o *BuildOptions) { o.SkipBackendBuild = true }},
+		{name: "bad binary name", opts: func(o *BuildOptions) { o.BackendBinaryName = "../backend" }},
+		{name: "bad port", opts: func(o *BuildOptions) { o.Backends = []string{"api:0"} }},
+		{name: "duplicate backend", opts: func(o *BuildOptions) { o.Backends = []string{"api:5001", "x/api:5002"} }},
+		{name: "duplicate port", opts: func(o *BuildOptions) { o.Backends = []string{"api:5001", "auth:5001"} }},
+		{name: "missing directory", opts: func(o *BuildOptions) { o.Backends = []string{"api"} }},
+	}
+
+	for _, tt := range tests {
+		b := newBuilder(DefaultOptions())
+		tt.opts(&b.options)
+		_, err := b.resolveBackends(t.TempDir(), "linux")
+		if tt.valid && err != nil {
+			t.Errorf("resolveBackends with %s: expected no error, but got %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("resolveBackends with %s: expected an error, but got none", tt.name)
+		}
+	}
+}
+
+// Test how --backend-cmd is split into arguments
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		line  string
+		want  []string
+		valid bool
+	}{
+		{line: "uvicorn app:main --port 5000", want: []string{"uvicorn", "app:main", "--port", "5000"}, valid: true},
+		{line: `  node  "my server.js"  `, want: []string{"node", "my server.js"}, valid: true},
+		{line: `sh -c 'echo "hi" $PORT'`, want: []string{"sh", "-c", `echo "hi" $PORT`}, valid: true},
+		{line: `run a\ b ""`, want: []string{"run", "a b", ""}, valid: true},
+		{line: `node "unterminated`},
+		{line: `trailing\`},
+	}
+
+	for _, tt := range tests {
+		got, err := splitCommand(tt.line)
+		if !tt.valid {
+			if err == nil {
+				t.Errorf("splitCommand(%q): expected an error, but got %q", tt.line, got)
+			}
+			continue
+		}
+		if err != nil || strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("splitCommand(%q): expected %q, but got %q (%v)", tt.line, tt.want, got, err)
+		}
+	}
+}
+
+// Test how --build-args are appended to the package manager's run command
+func TestFrontendBuildArgs(t *testing.T) {
+	tests := []struct {
+		pm   string
+		args string
+		want []string
+	}{
+		{pm: "npm", args: "", want: []string{"run", "build"}},
+		{pm: "npm", args: `--debug --config "prod config.js"`, want: []string{"run", "build", "--", "--debug", "--config", "prod config.js"}},
+		{pm: "pnpm", args: "--debug", want: []string{"run", "build", "--debug"}},
+		{pm: "yarn", args: "--debug", want: []string{"run", "build", "--debug"}},
+	}
+
+	for _, tt := range tests {
+		b := newBuilder(DefaultOptions())
+		b.options.BuildArgs = tt.args
+		got, err := b.frontendBuildArgs(tt.pm, "build")
+		if err != nil || strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("frontendBuildArgs(%q) with %q: expected %q, but got %q (%v)", tt.pm, tt.args, tt.want, got, err)
+		}
+	}
+}
+
+// Test that --frontend-install-cmd runs in --frontend-install-dir, and only
+// while node_modules is missing there, with the package manager detected there
+func TestInstallDependenciesCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the install command uses sh")
+	}
+
+	root := t.TempDir()
+	frontend := filepath.Join(root, "apps", "web")
+	if err := os.MkdirAll(frontend, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range map[string]string{
+		filepath.Join(frontend, "package.json"): `{"dependencies": {"next": "14.0.0"}}`,
+		filepath.Join(root, "package.json"):     `{"workspaces": ["apps/*"]}`,
+		filepath.Join(root, "pnpm-lock.yaml"):   "",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := newBuilder(DefaultOptions())
+	b.options.InstallCommand = `sh -c "mkdir node_modules && echo installed >> install.log"`
+	b.options.InstallDir = root
+	for i := 0; i < 2; i++ {
+		if err := b.installDependencies(context.Background(), frontend, "npm"); err != nil {
+			t.Fatalf("installDependencies: unexpected error: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "install.log"))
+	if err != nil || string(data) != "installed\n" {
+		t.Errorf("Expected the install command to run once in the install directory, but got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(frontend, "node_modules")); err == nil {
+		t.Errorf("Expected no node_modules in the frontend directory")
+	}
+	if pm := detectPackageManager(b.installDir(frontend)); pm != "pnpm" {
+		t.Errorf("Expected the package manager from the install directory's lockfile, but got %s", pm)
+	}
+}
+
+// Test that hooks run in order with the build's paths and stop at the first failure
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks use sh")
+	}
+	b := newBuilder(DefaultOptions())
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "hooks.log")
+	env := b.hookEnv(hookPaths{OutputDir: dir, Binary: filepath.Join(dir, "app")})
+	hooks := []string{
+		`sh -c "echo first $GONEXT_OUTPUT_BINARY >> ` + log + `"`,
+		`sh -c "exit 3"`,
+		`sh -c "echo never >> ` + log + `"`,
+	}
+
+	err := b.runHooks(context.Background(), "pre-build", hooks, env)
+	if err == nil || !strings.Contains(err.Error(), `pre-build hook "sh -c \"exit 3\"" failed`) {
+		t.Errorf("runHooks: expected the second hook to fail, but got %v", err)
+	}
+	data, _ := os.ReadFile(log)
+	if want := "first " + filepath.Join(dir, "app") + "\n"; string(data) != want {
+		t.Errorf("runHooks: expected hooks.log to contain %q, but got %q", want, data)
+	}
+}
+
+// Test that the commit is read from the given directory, not the working directory
+func TestGitCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", args[0], err, out)
+		}
+	}
+	rev := exec.Command("git", "rev-parse", "--short", "HEAD")
+	rev.Dir = repo
+	out, err := rev.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := gitCommit(repo), strings.TrimSpace(string(out)); got != want {
+		t.Errorf("gitCommit(repo): expected %s, but got %s", want, got)
+	}
+	if got := gitCommit(t.TempDir()); got != "unknown" {
+		t.Errorf("gitCommit outside a repository: expected unknown, but got %s", got)
+	}
+}
+
+// Test that clean only picks old gonext-* build directories
+func TestStaleTempDirs(t *testing.T) {
+	tempDir := t.TempDir()
+	old := time.Now().Add(-2 * time.Hour)
+	for name, modTime := range map[string]time.Time{
+		"gonext-123":        old,
+		"gonext-dry-run":    old,
+		"gonext-456":        time.Now(),
+		"gonext-backend-78": old,
+		"other-123":         old,
+	} {
+		dir := filepath.Join(tempDir, name)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(dir, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "gonext-file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := staleTempDirs(tempDir, time.Hour)
+	if err != nil {
+		t.Fatalf("staleTempDirs: unexpected error: %v", err)
+	}
+	want := []string{filepath.Join(tempDir, "gonext-123"), filepath.Join(tempDir, "gonext-dry-run")}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("staleTempDirs: expected %q, but got %q", want, got)
+	}
+}
+
+// Test that a copied frontend needs an index.html or SPA entry, plain or gzipped
+func TestCheckIndexFile(t *testing.T) {
+	tests := []struct {
+		file  string
+		valid bool
+	}{
+		{file: "index.html", valid: true},
+		{file: "index.html.gz", valid: true},
+		{file: "200.html", valid: true},
+		{file: "200.html.gz", valid: true},
+		{file: "home.html", valid: false},
+		{file: "index.html/page.html", valid: false},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		path := filepath.Join(dir, filepath.FromSlash(tt.file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("<html></html>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := checkIndexFile(dir, "200.html"); (err == nil) != tt.valid {
+			t.Errorf("checkIndexFile with %s: expected valid=%v, but got %v", tt.file, tt.valid, err)
+		}
+	}
+}
+
+// Test framework detection from package.json dependencies, scripts and config files
+func TestDetectFramework(t *testing.T) {
+	tests := []struct {
+		packageJSON string
+		configFile  string
+		want        string
+		wantErr     bool
+	}{
+		{packageJSON: `{"dependencies":{"next":"14.0.0","react":"18.0.0"}}`, want: "nextjs"},
+		{packageJSON: `{"devDependencies":{"vite":"5.0.0"}}`, want: "vite"},
+		{packageJSON: `{"dependencies":{"react-scripts":"5.0.0"}}`, want: "cra"},
+		{packageJSON: `{"devDependencies":{"@sveltejs/kit":"2.0.0","vite":"5.0.0"}}`, want: "sveltekit"},
+		{packageJSON: `{"scripts":{"build":"vite build"}}`, want: "vite"},
+		{packageJSON: `{"scripts":{"build":"tsc"}}`, configFile: "next.config.mjs", want: "nextjs"},
+		{packageJSON: `{"scripts":{"build":"tsc"}}`, want: ""},
+		{packageJSON: `{"dependencies":{"next":"14.0.0","vite":"5.0.0"}}`, wantErr: true},
+		{packageJSON: `{`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(tt.packageJSON), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if tt.configFile != "" {
+			if err := os.WriteFile(filepath.Join(dir, tt.configFile), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := detectFramework(dir)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("detectFramework with %s %s: expected %q (error: %v), but got %q, %v", tt.packageJSON, tt.configFile, tt.want, tt.wantErr, got, err)
+		}
+	}
+}
+
+// Test that the frontend cache key follows source changes but ignores
+// node_modules and the build output
+func TestFrontendHash(t *testing.T) {
+	tests := []struct {
+		file    string
+		changed bool
+	}{
+		{file: "src/page.tsx", changed: true},
+		{file: "package.json", changed: true},
+		{file: "src/new.css", changed: true},
+		{file: "node_modules/react/index.js", changed: false},
+		{file: "src/node_modules/x.js", changed: false},
+		{file: "out/index.html", changed: false},
+		{file: ".next/cache/x", changed: false},
+		{file: ".svelte-kit/generated/root.js", changed: false},
+		{file: "dist/index.html", changed: false},
+		{file: "build/index.html", changed: false},
+		{file: ".nuxt/x", changed: false},
+		{file: ".output/x", changed: false},
+		{file: ".astro/x", changed: false},
+		{file: "src/.cache/x", changed: false},
+		{file: "src/build/x.js", changed: true},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		for _, file := range []string{"package.json", "src/page.tsx", "node_modules/react/index.js", "out/index.html"} {
+			path := filepath.Join(dir, filepath.FromSlash(file))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		frontend := frontendSpec{Path: dir, framework: frontendFrameworks["nextjs"], pm: "npm"}
+		before, err := newBuilder(DefaultOptions()).frontendHash(frontend)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(tt.file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("changed"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		after, err := newBuilder(DefaultOptions()).frontendHash(frontend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (before != after) != tt.changed {
+			t.Errorf("frontendHash after writing %s: expected changed=%v, but got %v", tt.file, tt.changed, before != after)
+		}
+	}
+
+	// Switching the framework or output directory must not reuse the output
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash := func(framework, outDir string) string {
+		b := newBuilder(DefaultOptions())
+		b.options.OutDir = outDir
+		spec := frontendFrameworks[framework]
+		spec.Name = framework
+		h, err := b.frontendHash(frontendSpec{Path: dir, framework: spec, pm: "npm"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	if hash("cra", "") == hash("sveltekit", "") {
+		t.Errorf("frontendHash: expected different hashes for different frameworks")
+	}
+	if hash("vite", "") == hash("vite", "public-dist") {
+		t.Errorf("frontendHash: expected different hashes for different output directories")
+	}
+}
+
+// Test that HTML minification collapses whitespace without touching raw text or attribute values
+func TestMinifyHTML(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "<p>\n  hello   world\n</p>", want: "<p> hello world </p>"},
+		{in: "<div  class=\"a\"\n  id='b' >x</div >", want: "<div class=\"a\" id='b'>x</div>"},
+		{in: `<a title="two  spaces">x</a>`, want: `<a title="two  spaces">x</a>`},
+		{in: "<p>a<!-- note -->b</p>", want: "<p>ab</p>"},
+		{in: "<!--[if IE]><p>ie</p><![endif]-->", want: "<!--[if IE]><p>ie</p><![endif]-->"},
+		{in: "<pre>\n  keep\n    this\n</pre>", want: "<pre>\n  keep\n    this\n</pre>"},
+		{in: "<SCRIPT>if (a  <  b) {}</SCRIPT>  <p>x</p>", want: "<SCRIPT>if (a  <  b) {}</SCRIPT> <p>x</p>"},
+		{in: "<a href=x  />", want: "<a href=x />"},
+	}
+
+	for _, tt := range tests {
+		if got := string(minifyHTML([]byte(tt.in))); got != tt.want {
+			t.Errorf("minifyHTML(%q): expected %q, but got %q", tt.in, tt.want, got)
+		}
+	}
+}
+
+// Test how --exclude patterns match files and directories
+func TestExcluded(t *testing.T) {
+	patterns := []string{"videos/", "*.map", "/docs/*.pdf"}
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: "videos", isDir: true, want: true},
+		{path: "media/videos", isDir: true, want: true},
+		{path: "videos", isDir: false, want: false},
+		{path: "_next/static/app.js.map", want: true},
+		{path: "_next/static/app.js", want: false},
+		{path: "docs/guide.pdf", want: true},
+		{path: "blog/docs/guide.pdf", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := excluded(patterns, tt.path, tt.isDir); got != tt.want {
+			t.Errorf("excluded(%q, dir=%v): expected %v, but got %v", tt.path, tt.isDir, tt.want, got)
+		}
+	}
+}
+
+// Test that a failed build reports its error lines, or else its last lines
+func TestStderrCaptureSummary(t *testing.T) {
+	var noisy stderrCapture
+	fmt.Fprint(&noisy, "warn - compiling\nType error: Property 'x' does not exist\r\nwarn - done\nnpm ERR! code 1")
+	if got, want := noisy.Summary(), "\n  Type error: Property 'x' does not exist\n  npm ERR! code 1"; got != want {
+		t.Errorf("Expected error lines %q, but got %q", want, got)
+	}
+
+	var plain stderrCapture
+	for i := 0; i < stderrSummaryLines+5; i++ {
+		fmt.Fprintf(&plain, "line %d\n", i)
+	}
+	if got := plain.Summary(); !strings.HasPrefix(got, "\n  line 5\n") || !strings.HasSuffix(got, fmt.Sprintf("line %d", stderrSummaryLines+4)) {
+		t.Errorf("Expected the last %d lines, but got %q", stderrSummaryLines, got)
+	}
+
+	var empty stderrCapture
+	if got := empty.Summary(); got != "" {
+		t.Errorf("Expected an empty summary, but got %q", got)
+	}
+}
+
+// Test .env parsing of comments, exports and quoting
+func TestParseDotenv(t *testing.T) {
+	data := "# public config\n" +
+		"NEXT_PUBLIC_API_URL=https://api.example.com\n" +
+		"\n" +
+		"export NEXT_PUBLIC_NAME = My App # trailing comment\r\n" +
+		"SINGLE='literal \\n #kept'\n" +
+		"DOUBLE=\"two\\nlines\"\n" +
+		"EMPTY=\n"
+	want := []string{
+		"NEXT_PUBLIC_API_URL=https://api.example.com",
+		"NEXT_PUBLIC_NAME=My App",
+		`SINGLE=literal \n #kept`,
+		"DOUBLE=two\nlines",
+		"EMPTY=",
+	}
+
+	got, err := parseDotenv(data)
+	if err != nil {
+		t.Fatalf("parseDotenv: unexpected error: %v", err)
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("parseDotenv: expected %q, but got %q", want, got)
+	}
+
+	if _, err := parseDotenv("NOT A PAIR\n"); err == nil {
+		t.Errorf("parseDotenv: expected an error for a line without =")
+	}
+}
+
+// Test that the generated //go:embed directives only use forward slashes
+func TestGenerateMainEmbedDirectives(t *testing.T) {
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("out")
+	data.Frontends = []frontendSpec{{Dir: "out"}, {Dir: "docs", Prefix: "/docs"}}
+	data.Backends = []backendSpec{{Binary: "backend-binary.exe", Port: 5000}}
+	data.EmbedBackend = true
+
+	mainFile := filepath.Join(t.TempDir(), "main.go")
+	if err := newBuilder(DefaultOptions()).generateMain(mainFile, data); err != nil {
+		t.Fatalf("generateMain: unexpected error: %v", err)
+	}
+	src, err := os.ReadFile(mainFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var directives []string
+	for _, line := range strings.Split(string(src), "\n") {
+		if strings.HasPrefix(line, "//go:embed ") {
+			directives = append(directives, line)
+		}
+	}
+	want := []string{`//go:embed "all:out" "all:docs"`, `//go:embed "backends/backend-binary.exe"`}
+	if strings.Join(directives, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected directives %q, but got %q", want, directives)
+	}
+}
+
+// Test that frontend directories with spaces and unicode names embed and serve
+func TestGeneratedServerEmbedNames(t *testing.T) {
+	for _, dir := range []string{"my front end", "フロントエンド"} {
+		t.Run(dir, func(t *testing.T) {
+			data := newBuilder(DefaultOptions()).templateDataFromOptions(dir)
+			data.Backend = false
+
+			runGeneratedTest(t, data, map[string]string{"index.html": "<html>index</html>"}, `package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatalf("Failed to send GET request: %v", err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || !strings.Contains(string(body), "index") {
+		t.Errorf("Expected index.html with status 200, but got %d: %s", res.StatusCode, body)
+	}
+}
+`)
+		})
+	}
+}
+
+// Benchmark copying a synthetic frontend tree serially and with the worker pool
+func BenchmarkCopyDir(b *testing.B) {
+	const files, size = 200, 256 << 10
+	src := b.TempDir()
+	content := bytes.Repeat([]byte("x"), size)
+	for i := 0; i < files; i++ {
+		path := filepath.Join(src, fmt.Sprintf("dir%d", i%10), fmt.Sprintf("chunk%d.js", i))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for _, workers := range []int{1, runtime.NumCPU()} {
+		b.Run(fmt.Sprintf("workers-%d", workers), func(b *testing.B) {
+			defer func(n int) { copyWorkers = n }(copyWorkers)
+			copyWorkers = workers
+
+			b.SetBytes(files * size)
+			for i := 0; i < b.N; i++ {
+				if _, err := newBuilder(DefaultOptions()).copyDir(src, filepath.Join(b.TempDir(), "out"), copyOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Test that a client-side route colliding with a directory falls back to index.html
+func TestGeneratedServerDirectoryFallback(t *testing.T) {
+	files := map[string]string{
+		"index.html":     "<html>index</html>",
+		"blog/post.html": "<html>post</html>",
+	}
+
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backends = []backendSpec{{Binary: "backend-binary", Port: 5000}}
+	data.EmbedBackend = true
+
+	runGeneratedTest(t, data, files, `package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFileExistsDirectory(t *testing.T) {
+	fsys := fstest.MapFS{"blog/post.html": {Data: []byte("post")}}
+	if fileExists(fsys, "/blog") {
+		t.Errorf("Expected fileExists to be false for a directory")
+	}
+	if !fileExists(fsys, "/blog/post.html") {
+		t.Errorf("Expected fileExists to be true for a file")
+	}
+}
+
+func TestBlogRoute(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	// Don't follow redirects so a 301 to /blog/ is reported
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err := client.Get(ts.URL + "/blog")
+	if err != nil {
+		t.Fatalf("Failed to send GET request: %v", err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || !strings.Contains(string(body), "index") {
+		t.Errorf("Expected index.html with status 200, but got %d: %s", res.StatusCode, body)
+	}
+}
+`)
+}
+
+// Test that missing assets, and every missing path without SPA fallback, get 404.html
+func TestGeneratedServerNotFoundPage(t *testing.T) {
+	files := map[string]string{
+		"index.html": "<html>index</html>",
+		"404.html":   "<html>custom not found</html>",
+	}
+
+	tests := []struct {
+		name        string
+		spaFallback bool
+		path        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{name: "spa-client-route", spaFallback: true, path: "/dashboard", wantStatus: 200, wantBody: "index"},
+		{name: "spa-missing-asset", spaFallback: true, path: "/static/app.js", wantStatus: 404, wantBody: "custom not found"},
+		{name: "no-spa-missing-route", spaFallback: false, path: "/dashboard", wantStatus: 404, wantBody: "custom not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+			data.Backend = false
+			data.SPAFallback = tt.spaFallback
+
+			runGeneratedTest(t, data, files, fmt.Sprintf(`package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotFound(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %%v", err)
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + %q)
+	if err != nil {
+		t.Fatalf("Failed to send GET request: %%v", err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != %d || !strings.Contains(string(body), %q) {
+		t.Errorf("Expected status %%d with %%q, but got %%d: %%s", %d, %q, res.StatusCode, body)
+	}
+}
+`, tt.path, tt.wantStatus, tt.wantBody, tt.wantStatus, tt.wantBody))
+		})
+	}
+}
+
+// Test that directory URLs serve their index.html and redirect to the
+// canonical form for both trailing slash settings
+func TestGeneratedServerDirectoryIndex(t *testing.T) {
+	files := map[string]string{
+		"index.html":       "<html>index</html>",
+		"about/index.html": "<html>about</html>",
+	}
+
+	for _, trailingSlash := range []bool{false, true} {
+		t.Run(fmt.Sprintf("trailing-slash-%v", trailingSlash), func(t *testing.T) {
+			data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+			data.Backend = false
+			data.TrailingSlash = trailingSlash
+
+			// Requests as path, expected status, and body or Location
+			requests := `{"/about", 200, "about"}, {"/about/?q=1", 308, "../about?q=1"}, {"/dashboard", 200, "index"}`
+			if trailingSlash {
+				requests = `{"/about/", 200, "about"}, {"/about?q=1", 308, "about/?q=1"}, {"/dashboard", 200, "index"}`
+			}
+
+			runGeneratedTest(t, data, files, `package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDirectoryIndex(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	for _, tt := range []struct {
+		path   string
+		status int
+		want   string
+	}{`+requests+`} {
+		res, err := client.Get(ts.URL + tt.path)
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		got := string(body)
+		if tt.status == http.StatusPermanentRedirect {
+			got = res.Header.Get("Location")
+		}
+		if res.StatusCode != tt.status || !strings.Contains(got, tt.want) {
+			t.Errorf("GET %s: expected %d with %q, but got %d: %s", tt.path, tt.status, tt.want, res.StatusCode, got)
+		}
+	}
+}
+`)
+		})
+	}
+}
+
+// Test that extensionless URLs resolve to their .html pages before the SPA fallback
+func TestGeneratedServerHTMLExtension(t *testing.T) {
+	files := map[string]string{
+		"index.html":       "<html>index</html>",
+		"about.html":       "<html>about</html>",
+		"nested/page.html": "<html>nested page</html>",
+	}
+
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backend = false
+
+	runGeneratedTest(t, data, files, `package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTMLExtension(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	for path, want := range map[string]string{
+		"/about":         "about",
+		"/nested/page":   "nested page",
+		"/about.html":    "about",
+		"/missing/route": "index",
+	} {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK || !strings.Contains(string(body), want) {
+			t.Errorf("GET %s: expected %q with status 200, but got %d: %s", path, want, res.StatusCode, body)
+		}
+	}
+}
+`)
+}
+
+// Test that client-side routes get the configured SPA entry instead of index.html
+func TestGeneratedServerSPAEntry(t *testing.T) {
+	files := map[string]string{
+		"index.html": "<html>home</html>",
+		"200.html":   "<html>app shell</html>",
+	}
+
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backend = false
+	data.SPAEntry = "200.html"
+
+	runGeneratedTest(t, data, files, `package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSPAEntry(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	for path, want := range map[string]string{
+		"/":              "home",
+		"/dashboard":     "app shell",
+		"/users/42/edit": "app shell",
+	} {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK || !strings.Contains(string(body), want) {
+			t.Errorf("GET %s: expected %q with status 200, but got %d: %s", path, want, res.StatusCode, body)
+		}
+	}
+}
+`)
+}
+
+// Test that --strict-routes only falls back to the SPA entry for requests accepting HTML
+func TestGeneratedServerStrictRoutes(t *testing.T) {
+	files := map[string]string{
+		"index.html": "<html>home</html>",
+	}
+
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backend = false
+	data.StrictRoutes = true
+
+	runGeneratedTest(t, data, files, `package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStrictRoutes(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tests := []struct {
+		accept string
+		want   int
+	}{
+		{accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", want: http.StatusOK},
+		{accept: "TEXT/HTML", want: http.StatusOK},
+		{accept: "application/json", want: http.StatusNotFound},
+		{accept: "*/*", want: http.StatusNotFound},
+		{accept: "", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", ts.URL+"/dashboard", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != tt.want {
+			t.Errorf("GET /dashboard with Accept %q: expected status %d, but got %d", tt.accept, tt.want, res.StatusCode)
+		}
+	}
+}
+`)
+}
+
+// Test that web assets get their content types regardless of the host's mime database
+func TestGeneratedServerContentTypes(t *testing.T) {
+	files := map[string]string{
+		"index.html":          "<html>index</html>",
+		"app.js":              "export {}",
+		"module.mjs":          "export {}",
+		"main.wasm":           "\x00asm",
+		"site.webmanifest":    "{}",
+		"data.json":           "{}",
+		"assets/chunk.js.map": "{}",
+	}
+
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backend = false
+
+	runGeneratedTest(t, data, files, `package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContentTypes(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	for path, want := range map[string]string{
+		"/app.js":             "text/javascript; charset=utf-8",
+		"/module.mjs":         "text/javascript; charset=utf-8",
+		"/main.wasm":          "application/wasm",
+		"/site.webmanifest":   "application/manifest+json",
+		"/data.json":          "application/json",
+		"/assets/chunk.js.map": "application/json",
+	} {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+		res.Body.Close()
+
+		if got := res.Header.Get("Content-Type"); got != want {
+			t.Errorf("GET %s: expected Content-Type %q, but got %q", path, want, got)
+		}
+	}
+}
+`)
+}
+
+// Test that files under _next and dotfiles such as .well-known are embedded
+// and served, not replaced by the SPA entry
+func TestGeneratedServerUnderscoreAndDotfiles(t *testing.T) {
+	files := map[string]string{
+		"index.html":                "<html>index</html>",
+		"_next/static/app.js":       "next chunk",
+		".well-known/security.txt":  "Contact: security@example.com",
+		".well-known/.hidden/token": "token",
+	}
+
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backend = false
+
+	runGeneratedTest(t, data, files, `package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUnderscoreAndDotfiles(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	for path, want := range map[string]string{
+		"/_next/static/app.js":       "next chunk",
+		"/.well-known/security.txt":  "Contact: security@example.com",
+		"/.well-known/.hidden/token": "token",
+	} {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK || string(body) != want {
+			t.Errorf("GET %s: expected 200 with %q, but got %d: %s", path, want, res.StatusCode, body)
+		}
+	}
+}
+`)
+}
+
+// Test that embedded media is served in ranges and never falls back to the SPA entry
+func TestGeneratedServerRange(t *testing.T) {
+	files := map[string]string{
+		"index.html":      "<html>index</html>",
+		"media/intro.mp4": strings.Repeat("0123456789", 300),
+	}
+
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backend = false
+	data.Compression = true
+
+	runGeneratedTest(t, data, files, `package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRange(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	get := func(path string, header map[string]string) (*http.Response, string) {
+		req, err := http.NewRequest("GET", ts.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res, string(body)
+	}
+
+	res, _ := get("/media/intro.mp4", nil)
+	etag := res.Header.Get("ETag")
+	if res.Header.Get("Accept-Ranges") != "bytes" || etag == "" || etag[0] == 'W' {
+		t.Errorf("Expected a strong ETag and Accept-Ranges: bytes, but got %q and %q", etag, res.Header.Get("Accept-Ranges"))
+	}
+
+	for _, header := range []map[string]string{
+		{"Range": "bytes=10-19", "Accept-Encoding": "gzip"},
+		{"Range": "bytes=10-19", "If-Range": etag},
+	} {
+		res, body := get("/media/intro.mp4?t=5", header)
+		if res.StatusCode != http.StatusPartialContent || body != "0123456789" || res.Header.Get("Content-Range") != "bytes 10-19/3000" {
+			t.Errorf("GET with %v: expected 206 with bytes 10-19, but got %d with %d bytes (%s)", header, res.StatusCode, len(body), res.Header.Get("Content-Range"))
+		}
+	}
+
+	for _, tc := range []struct {
+		path        string
+		rangeHeader string
+	}{
+		{path: "/media/intro.mp4/"},
+		{path: "/media/missing", rangeHeader: "bytes=0-"},
+	} {
+		res, body := get(tc.path, map[string]string{"Range": tc.rangeHeader})
+		if res.StatusCode != http.StatusNotFound || body == "<html>index</html>" {
+			t.Errorf("GET %s: expected 404 rather than the SPA entry, but got %d", tc.path, res.StatusCode)
+		}
+	}
+}
+`)
+}
+
+// Test that compressible responses are gzipped only for clients that accept it
+func TestGeneratedServerCompression(t *testing.T) {
+	files := map[string]string{
+		"index.html":      "<html>index</html>",
+		"assets/app.js":   strings.Repeat("console.log(\"hello\");\n", 100),
+		"assets/small.js": "console.log(\"hi\");",
+	}
+
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backend = false
+	data.Compression = true
+
+	runGeneratedTest(t, data, files, `package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	// Keep the transport from negotiating and decoding gzip itself
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	want := strings.Repeat("console.log(\"hello\");\n", 100)
+
+	for _, tt := range []struct {
+		path           string
+		acceptEncoding string
+		gzipped        bool
+	}{
+		{"/assets/app.js", "gzip, deflate", true},
+		{"/assets/app.js", "br;q=1, gzip;q=0.5", true},
+		{"/assets/app.js", "", false},
+		{"/assets/app.js", "gzip;q=0", false},
+		{"/assets/app.js", "deflate", false},
+		{"/assets/small.js", "gzip", false},
+	} {
+		req, err := http.NewRequest("GET", ts.URL+tt.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+
+		var body io.Reader = res.Body
+		gzipped := res.Header.Get("Content-Encoding") == "gzip"
+		if gzipped {
+			zr, err := gzip.NewReader(res.Body)
+			if err != nil {
+				t.Fatalf("GET %s: invalid gzip body: %v", tt.path, err)
+			}
+			body = zr
+		}
+		content, err := io.ReadAll(body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatalf("GET %s: failed to read body: %v", tt.path, err)
+		}
+
+		if gzipped != tt.gzipped {
+			t.Errorf("GET %s (Accept-Encoding: %q): expected gzipped=%v, but got Content-Encoding %q", tt.path, tt.acceptEncoding, tt.gzipped, res.Header.Get("Content-Encoding"))
+		}
+		if !strings.Contains(res.Header.Get("Vary"), "Accept-Encoding") {
+			t.Errorf("GET %s (Accept-Encoding: %q): expected Vary: Accept-Encoding, but got %q", tt.path, tt.acceptEncoding, res.Header.Get("Vary"))
+		}
+		if tt.path == "/assets/app.js" && string(content) != want {
+			t.Errorf("GET %s (Accept-Encoding: %q): body does not match the embedded file", tt.path, tt.acceptEncoding)
+		}
+	}
+}
+`)
+}
+
+// Test that static responses carry content-hash ETags and revalidate with 304
+func TestGeneratedServerETag(t *testing.T) {
+	files := map[string]string{
+		"index.html":    "<html>index</html>",
+		"assets/app.js": "console.log(\"hello\");",
+	}
+
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backend = false
+
+	runGeneratedTest(t, data, files, `package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	get := func(path, ifNoneMatch string) (*http.Response, string) {
+		req, err := http.NewRequest("GET", ts.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res, string(body)
+	}
+
+	etags := make(map[string]bool)
+	for _, path := range []string{"/", "/assets/app.js"} {
+		res, _ := get(path, "")
+		etag := res.Header.Get("ETag")
+		if res.StatusCode != http.StatusOK || etag == "" {
+			t.Fatalf("GET %s: expected 200 with an ETag, but got %d with %q", path, res.StatusCode, etag)
+		}
+		if etags[etag] {
+			t.Errorf("GET %s: expected a distinct ETag per file, but got %q twice", path, etag)
+		}
+		etags[etag] = true
+
+		if again, _ := get(path, ""); again.Header.Get("ETag") != etag {
+			t.Errorf("GET %s: expected a stable ETag %q, but got %q", path, etag, again.Header.Get("ETag"))
+		}
+
+		res, body := get(path, etag)
+		if res.StatusCode != http.StatusNotModified || body != "" {
+			t.Errorf("GET %s with If-None-Match %s: expected 304 with no body, but got %d: %s", path, etag, res.StatusCode, body)
+		}
+
+		res, body = get(path, "\"stale\"")
+		if res.StatusCode != http.StatusOK || body == "" {
+			t.Errorf("GET %s with a stale If-None-Match: expected 200 with the file, but got %d", path, res.StatusCode)
+		}
+	}
+}
+`)
+}
+
+// Test that the bundle loads its runtime .env without overriding set variables
+func TestGeneratedServerDotenv(t *testing.T) {
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backend = false
+	data.Dotenv = true
+
+	runGeneratedTest(t, data, map[string]string{"index.html": "<html>index</html>"}, `package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDotenv(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.env")
+	env := "# runtime config\nDATABASE_URL=postgres://db/app\nexport GREETING=\"hello\\nworld\"\nPORT=9000\n"
+	if err := os.WriteFile(name, []byte(env), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DOTENV_PATH", name)
+	t.Setenv("PORT", "8081")
+	t.Setenv("DATABASE_URL", "")
+	os.Unsetenv("DATABASE_URL")
+	t.Setenv("GREETING", "")
+	os.Unsetenv("GREETING")
+
+	if err := loadDotenv(); err != nil {
+		t.Fatalf("loadDotenv: unexpected error: %v", err)
+	}
+	for key, want := range map[string]string{
+		"DATABASE_URL": "postgres://db/app",
+		"GREETING":     "hello\nworld",
+		"PORT":         "8081",
+	} {
+		if got := os.Getenv(key); got != want {
+			t.Errorf("%s: expected %q, but got %q", key, want, got)
+		}
+	}
+
+	t.Setenv("DOTENV_PATH", filepath.Join(t.TempDir(), "missing.env"))
+	if err := loadDotenv(); err == nil {
+		t.Errorf("loadDotenv: expected an error for a missing DOTENV_PATH")
+	}
+}
+`)
+}
+
+// Test that the framework asset directory is found in the embedded frontend
+func TestGeneratedServerAssetDir(t *testing.T) {
+	files := map[string]string{
+		"index.html":                  "<html>index</html>",
+		"_next/static/chunks/main.js": "console.log(1)",
+		"empty/.keep":                 "",
+	}
+
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backend = false
+	data.Frontends = []frontendSpec{{Dir: "front-end", AssetDir: "_next/static"}}
+
+	runGeneratedTest(t, data, files, `package main
+
+import (
+	"io/fs"
+	"testing"
+)
+
+func TestHasFiles(t *testing.T) {
+	fsys, err := fs.Sub(frontendFS, frontends[0].dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasFiles(fsys, frontends[0].assets) {
+		t.Errorf("Expected %s to be embedded", frontends[0].assets)
+	}
+	if hasFiles(fsys, "assets") {
+		t.Errorf("Expected a missing directory to have no files")
+	}
+}
+`)
+}
+
+// Test that the shutdown signal the bundle receives is forwarded to the backend
+func TestGeneratedServerSignalForwarding(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups and SIGINT forwarding are unix-only")
+	}
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	script := `trap 'echo INT > "$SIGNAL_FILE"; exit 0' INT; trap 'echo TERM > "$SIGNAL_FILE"; exit 0' TERM; while :; do sleep 0.1; done`
+	data.Backends = []backendSpec{{Binary: "sh", Port: 5000, Command: []string{"sh", "-c", script}}}
+
+	runGeneratedTest(t, data, map[string]string{"index.html": "<html>index</html>"}, `package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignalForwarding(t *testing.T) {
+	signalFile := filepath.Join(t.TempDir(), "signal")
+	t.Setenv("SIGNAL_FILE", signalFile)
+
+	cmd, err := startBackend(0)
+	if err != nil {
+		t.Fatalf("Failed to start backend: %v", err)
+	}
+	s := newBackendSupervisor(0, cmd)
+	// Give the shell time to install its traps
+	time.Sleep(300 * time.Millisecond)
+
+	// The backend runs in its own process group, so only the bundle gets this
+	syscall.Kill(os.Getpid(), syscall.SIGINT)
+	for deadline := time.Now().Add(5 * time.Second); !s.stopping(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Supervisor did not see the interrupt")
+		}
+	}
+	s.stop()
+
+	got, err := os.ReadFile(signalFile)
+	if err != nil {
+		t.Fatalf("Backend did not record a signal: %v", err)
+	}
+	if strings.TrimSpace(string(got)) != "INT" {
+		t.Errorf("Expected the backend to receive INT, but got %q", got)
+	}
+}
+`)
+}
+
+// Test that a stopped supervisor stops listening for signals instead of
+// leaving a goroutine behind
+func TestGeneratedServerSupervisorSignalCleanup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the backend uses sh")
+	}
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backends = []backendSpec{{Binary: "sh", Port: 5000, Command: []string{"sh", "-c", "trap 'exit 0' TERM; while :; do sleep 0.1; done"}}}
+
+	runGeneratedTest(t, data, map[string]string{"index.html": "<html>index</html>"}, `package main
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func supervise(t *testing.T) {
+	cmd, err := startBackend(0)
+	if err != nil {
+		t.Fatalf("Failed to start backend: %v", err)
+	}
+	newBackendSupervisor(0, cmd).stop()
+}
+
+func TestSupervisorSignalCleanup(t *testing.T) {
+	// The first supervisor starts the os/signal goroutine, which stays
+	supervise(t)
+	before := runtime.NumGoroutine()
+	for i := 0; i < 5; i++ {
+		supervise(t)
+	}
+	for deadline := time.Now().Add(5 * time.Second); runtime.NumGoroutine() > before; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d goroutines after stopping the supervisors, but got %d", before, runtime.NumGoroutine())
+		}
+	}
+}
+`)
+}
+
+// Test that stopping the backend also kills the processes it started
+func TestGeneratedServerBackendProcessTree(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("checks process state in /proc")
+	}
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	// The child ignores SIGTERM and would outlive the backend without the group kill
+	script := `sh -c 'trap "" TERM; while :; do sleep 0.1; done' & echo $! > "$CHILD_PID_FILE"; trap 'exit 0' TERM; while :; do sleep 0.1; done`
+	data.Backends = []backendSpec{{Binary: "sh", Port: 5000, Command: []string{"sh", "-c", script}}}
+
+	runGeneratedTest(t, data, map[string]string{"index.html": "<html>index</html>"}, `package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Reports whether pid is running; zombies that nothing reaps count as dead
+func running(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	return err == nil && !strings.Contains(string(stat), ") Z ")
+}
+
+func TestBackendProcessTree(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	t.Setenv("CHILD_PID_FILE", pidFile)
+
+	cmd, err := startBackend(0)
+	if err != nil {
+		t.Fatalf("Failed to start backend: %v", err)
+	}
+	s := newBackendSupervisor(0, cmd)
+
+	var child int
+	for deadline := time.Now().Add(5 * time.Second); child == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Backend did not start its child process")
+		}
+		data, _ := os.ReadFile(pidFile)
+		child, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+	defer syscall.Kill(child, syscall.SIGKILL)
+
+	s.stop()
+	for deadline := time.Now().Add(5 * time.Second); running(child); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Child process outlived the stopped backend")
+		}
+	}
+}
+`)
+}
+
+// Test that WebSocket upgrades pass through the API proxy, including behind the access log
+func TestGeneratedServerWebSocketProxy(t *testing.T) {
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backends = []backendSpec{{Binary: "backend-binary", Port: 5000}}
+	data.EmbedBackend = true
+
+	runGeneratedTest(t, data, map[string]string{"index.html": "<html>index</html>"}, `package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestWebSocketProxy(t *testing.T) {
+	// Backend that completes the handshake and echoes frames back
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/ws" || r.Header.Get("Upgrade") != "websocket" {
+			http.Error(w, "expected a websocket upgrade", http.StatusBadRequest)
+			return
+		}
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Failed to hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		brw.WriteString("Sec-WebSocket-Accept: " + acceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		brw.Flush()
+		io.Copy(conn, brw)
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+	t.Setenv("BACKEND_PORT", backendURL.Port())
+
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	ts := httptest.NewServer(accessLogHandler(mux))
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	req, _ := http.NewRequest("GET", ts.URL+"/api/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatalf("Failed to read the handshake response: %v", err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols || res.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		t.Fatalf("Expected a 101 handshake, but got %d with headers %v", res.StatusCode, res.Header)
+	}
+
+	// A masked text frame carrying "hi"
+	frame := []byte{0x81, 0x82, 1, 2, 3, 4, 'h' ^ 1, 'i' ^ 2}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	echo := make([]byte, len(frame))
+	if _, err := io.ReadFull(br, echo); err != nil {
+		t.Fatalf("Failed to read the echoed frame: %v", err)
+	}
+	if string(echo) != string(frame) {
+		t.Errorf("Expected the frame %v echoed, but got %v", frame, echo)
+	}
+}
+`)
+}
+
+// Test that health check results are reused until the cache TTL expires
+func TestGeneratedServerHealthzCache(t *testing.T) {
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backends = []backendSpec{{Binary: "backend-binary", Port: 5000}}
+	data.EmbedBackend = true
+	data.BackendHealthPath = "/ready"
+	data.HealthzCacheTTL = 500 * time.Millisecond
+
+	runGeneratedTest(t, data, map[string]string{"index.html": "<html>index</html>"}, `package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthzCache(t *testing.T) {
+	var healthy, probes atomic.Int32
+	healthy.Store(1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		if healthy.Load() == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer backend.Close()
+	u, _ := url.Parse(backend.URL)
+	t.Setenv("BACKEND_PORT", u.Port())
+
+	handler := healthzHandler(nil)
+	check := func(want int) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+		if rec.Code != want {
+			t.Errorf("Expected %d, but got %d: %s", want, rec.Code, rec.Body)
+		}
+	}
+
+	check(200)
+	healthy.Store(0)
+	check(200)
+	if n := probes.Load(); n != 1 {
+		t.Errorf("Expected a cached result within the TTL, but the backend was probed %d times", n)
+	}
+
+	time.Sleep(healthzCacheTTL)
+	check(503)
+	healthy.Store(1)
+	check(503)
+
+	time.Sleep(healthzCacheTTL)
+	check(200)
+	if n := probes.Load(); n != 3 {
+		t.Errorf("Expected one probe per TTL, but the backend was probed %d times", n)
+	}
+}
+`)
+}
+
+// Test that a base path is stripped before routing and other paths are not served
+func TestGeneratedServerBasePath(t *testing.T) {
+	files := map[string]string{
+		"index.html":       "<html>index</html>",
+		"about/index.html": "<html>about</html>",
+	}
+
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backend = false
+	data.BasePath = "/dashboard"
+
+	runGeneratedTest(t, data, files, `package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBasePath(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	ts := httptest.NewServer(basePathHandler(mux))
+	defer ts.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	for _, tt := range []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"/dashboard/", 200, "index"},
+		{"/dashboard/about", 200, "about"},
+		{"/dashboard/missing/route", 200, "index"},
+		{"/dashboard?q=1", 308, "/dashboard/?q=1"},
+		{"/about", 404, ""},
+		{"/dashboardx", 404, ""},
+	} {
+		res, err := client.Get(ts.URL + tt.path)
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		got := string(body)
+		if tt.status == http.StatusPermanentRedirect {
+			got = res.Header.Get("Location")
+		}
+		if res.StatusCode != tt.status || !strings.Contains(got, tt.want) {
+			t.Errorf("GET %s: expected %d with %q, but got %d: %s", tt.path, tt.status, tt.want, res.StatusCode, got)
+		}
+	}
+}
+`)
+}
+
+// Test that basic auth rejects missing or wrong credentials and accepts matching ones
+func TestGeneratedServerBasicAuth(t *testing.T) {
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backend = false
+
+	runGeneratedTest(t, data, map[string]string{"index.html": "<html>index</html>"}, `package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	ts := httptest.NewServer(basicAuthHandler("admin", "secret", mux))
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		name   string
+		user   string
+		pass   string
+		auth   bool
+		status int
+	}{
+		{name: "no credentials", status: 401},
+		{name: "wrong password", user: "admin", pass: "wrong", auth: true, status: 401},
+		{name: "wrong user", user: "root", pass: "secret", auth: true, status: 401},
+		{name: "password prefix", user: "admin", pass: "secre", auth: true, status: 401},
+		{name: "empty credentials", auth: true, status: 401},
+		{name: "matching credentials", user: "admin", pass: "secret", auth: true, status: 200},
+	} {
+		req, err := http.NewRequest("GET", ts.URL+"/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.auth {
+			req.SetBasicAuth(tt.user, tt.pass)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != tt.status {
+			t.Errorf("%s: expected %d, but got %d", tt.name, tt.status, res.StatusCode)
+		}
+		if tt.status == http.StatusUnauthorized {
+			if res.Header.Get("WWW-Authenticate") == "" || string(body) == "<html>index</html>" {
+				t.Errorf("%s: expected a WWW-Authenticate challenge without the page, but got %q: %s", tt.name, res.Header.Get("WWW-Authenticate"), body)
+			}
+		} else if string(body) != "<html>index</html>" {
+			t.Errorf("%s: expected the index page, but got %s", tt.name, body)
+		}
+	}
+}
+`)
+}
+
+// Test that basic auth leaves the health endpoint open under a base path
+func TestGeneratedServerBasePathBasicAuth(t *testing.T) {
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backend = false
+	data.BasePath = "/app"
+
+	runGeneratedTest(t, data, map[string]string{"index.html": "<html>index</html>"}, `package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasePathBasicAuth(t *testing.T) {
+	mux, err := startServer()
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	// Wrapped as in main: credentials are checked before the base path is stripped
+	ts := httptest.NewServer(basicAuthHandler("admin", "secret", basePathHandler(mux)))
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		path   string
+		auth   bool
+		status int
+	}{
+		{"/app/healthz", false, 200},
+		{"/app/", false, 401},
+		{"/app/", true, 200},
+		{"/healthz", false, 401},
+	} {
+		req, err := http.NewRequest("GET", ts.URL+tt.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.auth {
+			req.SetBasicAuth("admin", "secret")
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+		res.Body.Close()
+		if res.StatusCode != tt.status {
+			t.Errorf("GET %s (credentials: %v): expected %d, but got %d", tt.path, tt.auth, tt.status, res.StatusCode)
+		}
+	}
+}
+`)
+}
+
+// Test that external backend binaries resolve next to the executable, not the working directory
+func TestGeneratedServerBackendBinaryPath(t *testing.T) {
+	data := newBuilder(DefaultOptions()).templateDataFromOptions("front-end")
+	data.Backends = []backendSpec{{Binary: "backend-binary", Port: 5000}}
+	data.EmbedBackend = false
+
+	runGeneratedTest(t, data, map[string]string{"index.html": "<html>index</html>"}, `package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackendBinaryPath(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Simulate a service manager launching the bundle from elsewhere
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	got, err := getBackendBinaryName("backend-binary")
+	if err != nil {
+		t.Fatalf("Failed to resolve the backend binary: %v", err)
+	}
+	if want := filepath.Join(filepath.Dir(exe), "backend-binary"); got != want {
+		t.Errorf("Expected %s, but got %s", want, got)
+	}
+}
+`)
+}
+
+// Renders mainTemplate into a temporary module with the given frontend files
+// and runs testSrc against the generated server with go test
+func runGeneratedTest(t *testing.T, data mainTemplateData, files map[string]string, testSrc string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping generated server test in short mode")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, data.FrontendDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if data.EmbedBackend {
+		for _, backend := range data.Backends {
+			path := filepath.Join(dir, "backends", backend.Binary)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte("backend"), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := newBuilder(DefaultOptions()).generateMain(filepath.Join(dir, "main.go"), data); err != nil {
+		t.Fatalf("Failed to generate main.go: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module gonext\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main_test.go"), []byte(testSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "test", ".")
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Generated server tests failed: %v\n%s", err, output)
+	}
+}
@@ -0,0 +1,157 @@
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Directories of a frontend project that never affect its build output, at
+// any depth: dependencies, version control and tool caches
+var cacheIgnoredDirs = map[string]bool{
+	"node_modules": true,
+	".git":         true,
+	".cache":       true,
+	".turbo":       true,
+	".nuxt":        true,
+	".output":      true,
+	".astro":       true,
+}
+
+// Returns the top-level directories of frontendPath that hold generated files
+// rather than sources: the configured build output, and the output and cache
+// directories of every supported framework, since a project may have been
+// built with another one before
+func (b *builder) generatedDirs(frontend frontendSpec) map[string]bool {
+	dirs := map[string]bool{b.frontendOutputPath(frontend.Path, frontend.framework): true}
+	for _, framework := range frontendFrameworks {
+		dirs[filepath.Join(frontend.Path, framework.OutDir)] = true
+		if framework.CacheDir != "" {
+			dirs[filepath.Join(frontend.Path, framework.CacheDir)] = true
+		}
+	}
+	return dirs
+}
+
+// Returns the directory frontend builds are cached in: --cache-dir, or
+// gonext/frontends in the OS cache directory
+func (b *builder) frontendCacheDir() (string, error) {
+	if b.options.CacheDir != "" {
+		return filepath.Join(b.options.CacheDir, "frontends"), nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gonext", "frontends"), nil
+}
+
+// Hashes everything that determines the build output of frontend: its source
+// files (without node_modules and generated directories), the framework, its
+// output directory, build script and arguments, and the --base-path, --env and
+// --env-file values. Variables inherited from the shell are not part of the key.
+func (b *builder) frontendHash(frontend frontendSpec) (string, error) {
+	output := b.frontendOutputPath(frontend.Path, frontend.framework)
+	outputRel, err := filepath.Rel(frontend.Path, output)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "framework=%s\nout=%s\n", frontend.framework.Name, filepath.ToSlash(outputRel))
+	fmt.Fprintf(h, "script=%s\nargs=%s\npm=%s\nbase=%s\n", frontend.framework.Script, b.options.BuildArgs, frontend.pm, normalizeAPIPrefix(b.options.BasePath))
+	for _, entry := range b.options.FrontendEnv {
+		fmt.Fprintf(h, "env=%s\n", entry)
+	}
+	if b.options.FrontendEnvFile != "" {
+		data, err := os.ReadFile(b.options.FrontendEnvFile)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "env-file=%d\n", len(data))
+		h.Write(data)
+	}
+
+	generated := b.generatedDirs(frontend)
+	err = filepath.WalkDir(frontend.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != frontend.Path && (cacheIgnoredDirs[d.Name()] || generated[path]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(frontend.Path, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			// Symlinks and other special files contribute only their name
+			fmt.Fprintf(h, "other=%s\n", filepath.ToSlash(rel))
+			return nil
+		}
+		fmt.Fprintf(h, "file=%s %d %v\n", filepath.ToSlash(rel), info.Size(), info.Mode().Perm()&0111 != 0)
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Returns the cached build output for the frontend hash, or "" when there is none
+func (b *builder) cachedFrontend(hash string) string {
+	dir, err := b.frontendCacheDir()
+	if err != nil {
+		return ""
+	}
+	out := filepath.Join(dir, hash)
+	if info, err := os.Stat(out); err != nil || !info.IsDir() {
+		return ""
+	}
+	return out
+}
+
+// Copies the built output of frontend into the cache under hash. The copy is
+// renamed into place once complete, so an interrupted store is never reused.
+func (b *builder) storeFrontendCache(frontend frontendSpec, hash string) error {
+	dir, err := b.frontendCacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(dir, "."+hash+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	out := filepath.Join(tmp, "out")
+	if _, err := b.copyDir(b.frontendOutputPath(frontend.Path, frontend.framework), out, copyOptions{}); err != nil {
+		return err
+	}
+	err = os.Rename(out, filepath.Join(dir, hash))
+	if err != nil && b.cachedFrontend(hash) != "" {
+		// Another build stored the same output first
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CleanOptions configures Clean
+type CleanOptions struct {
+	CacheDir  string        // build cache to clear, as given to BuildOptions.CacheDir
+	OlderThan time.Duration // keep temp directories modified more recently, e.g. by a running build
+	DryRun    bool          // only log what would be removed
+	Verbose   bool
+	Quiet     bool
+}
+
+// Clean removes the temp directories left behind by interrupted builds and
+// clears the frontend build cache
+func Clean(opts CleanOptions) error {
+	buildOpts := DefaultOptions()
+	buildOpts.CacheDir, buildOpts.DryRun = opts.CacheDir, opts.DryRun
+	buildOpts.Verbose, buildOpts.Quiet = opts.Verbose, opts.Quiet
+	b := newBuilder(buildOpts)
+
+	targets, err := staleTempDirs(os.TempDir(), opts.OlderThan)
+	if err != nil {
+		return fmt.Errorf("failed to list temp directories: %w", err)
+	}
+	cacheDir, err := b.frontendCacheDir()
+	if err != nil {
+		return fmt.Errorf("failed to locate the build cache: %w", err)
+	}
+	if _, err := os.Stat(cacheDir); err == nil {
+		targets = append(targets, cacheDir)
+	}
+
+	var freed int64
+	var errs []error
+	for _, target := range targets {
+		size := dirSize(target)
+		if opts.DryRun {
+			b.infof("[dry-run] would remove %s (%s)", target, FormatBytes(size))
+			continue
+		}
+		if err := os.RemoveAll(target); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		b.debugf("Removed %s (%s)", target, FormatBytes(size))
+		freed += size
+	}
+	if len(targets) == 0 {
+		b.infof("Nothing to clean")
+	} else if !opts.DryRun {
+		b.infof("Removed %d directories, freeing %s", len(targets)-len(errs), FormatBytes(freed))
+	}
+	return errors.Join(errs...)
+}
+
+// Returns the gonext-* build directories in tempDir last modified at least
+// olderThan ago. gonext-backend-* directories hold the backends of bundles,
+// which may still be running, so they are left alone.
+func staleTempDirs(tempDir string, olderThan time.Duration) ([]string, error) {
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || !strings.HasPrefix(name, "gonext-") || strings.HasPrefix(name, "gonext-backend-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < olderThan {
+			continue
+		}
+		dirs = append(dirs, filepath.Join(tempDir, name))
+	}
+	return dirs, nil
+}
+
+// Sums the sizes of the files below dir, skipping unreadable ones
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
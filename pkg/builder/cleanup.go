@@ -0,0 +1,34 @@
+package builder
+
+import (
+	"os/exec"
+)
+
+// Registers fn to run when the build finishes, fails or is interrupted
+func (b *builder) addCleanup(fn func()) {
+	b.cleanupMu.Lock()
+	defer b.cleanupMu.Unlock()
+	b.cleanupFuncs = append(b.cleanupFuncs, fn)
+}
+
+// Runs the registered cleanup functions in reverse order, at most once each
+func (b *builder) runCleanup() {
+	b.cleanupMu.Lock()
+	funcs := b.cleanupFuncs
+	b.cleanupFuncs = nil
+	b.cleanupMu.Unlock()
+
+	for i := len(funcs) - 1; i >= 0; i-- {
+		funcs[i]()
+	}
+}
+
+// Runs cmd in its own process group, so cancelling the context it was
+// created with kills its children along with it
+func runProcessGroup(cmd *exec.Cmd) error {
+	setProcessGroup(cmd)
+	if cmd.Cancel != nil {
+		cmd.Cancel = func() error { return killProcessTree(cmd) }
+	}
+	return cmd.Run()
+}
@@ -0,0 +1,345 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// How long to wait for file changes to settle before rebuilding
+const devDebounce = 300 * time.Millisecond
+
+// Directories never watched for changes
+var devIgnoredDirs = map[string]bool{
+	"node_modules": true,
+	".git":         true,
+	".next":        true,
+}
+
+// DevOptions configures Dev. Only the frontend, backend and API proxy
+// options of BuildOptions apply.
+type DevOptions struct {
+	BuildOptions
+	Port int  // port for the development server
+	Open bool // open the development server in the default browser once it is listening
+}
+
+// Dev builds the frontend and backend, serves them locally and rebuilds on
+// changes until ctx is cancelled
+func Dev(ctx context.Context, opts DevOptions) error {
+	b := newBuilder(opts.BuildOptions)
+	backendPath, frontendPath := opts.BackendPath, opts.FrontendPath
+
+	defer b.runCleanup()
+
+	framework, pm, err := b.resolveFrontend(frontendPath)
+	if err != nil {
+		return err
+	}
+	if err := checkBackend(backendPath); err != nil {
+		return fmt.Errorf("invalid backend: %w", err)
+	}
+	staticDir := b.frontendOutputPath(frontendPath, framework)
+
+	tempDir, err := os.MkdirTemp("", "gonext-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	b.addCleanup(func() { os.RemoveAll(tempDir) })
+
+	if err := b.installDependencies(ctx, frontendPath, pm); err != nil {
+		return fmt.Errorf("failed to install frontend dependencies: %w", err)
+	}
+	if err := b.buildNextJS(ctx, frontendPath, pm, framework); err != nil {
+		return fmt.Errorf("failed to build frontend: %w", err)
+	}
+
+	backend := &devBackend{b: b, dir: tempDir}
+	b.addCleanup(backend.stop)
+	if err := backend.rebuild(ctx, backendPath); err != nil {
+		return fmt.Errorf("failed to build backend: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", opts.Port),
+		Handler: b.devHandler(staticDir),
+	}
+	// Listen before serving so --open only fires once the port is bound
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return fmt.Errorf("development server failed: %w", err)
+	}
+	defer server.Close()
+	// Closing the watcher stops watching, so Dev returns the serve error
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			serveErr <- err
+			watcher.Close()
+		}
+	}()
+	devURL := fmt.Sprintf("http://localhost:%d", opts.Port)
+	b.infof("Development server running on %s", devURL)
+	if opts.Open {
+		if err := openBrowser(devURL); err != nil {
+			b.infof("Failed to open the browser: %v", err)
+		}
+	}
+
+	w := &devWatcher{
+		b:        b,
+		watcher:  watcher,
+		backend:  absPath(backendPath),
+		frontend: absPath(frontendPath),
+		output:   absPath(staticDir),
+	}
+	for _, root := range []string{w.backend, w.frontend} {
+		if err := w.add(root); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", root, err)
+		}
+	}
+	b.infof("Watching %s and %s for changes", backendPath, frontendPath)
+
+	w.run(ctx, func(frontendChanged, backendChanged bool) {
+		if frontendChanged {
+			b.infof("Frontend changed, rebuilding...")
+			if err := b.buildNextJS(ctx, frontendPath, pm, framework); err != nil {
+				b.infof("Frontend build failed: %v", err)
+			} else {
+				b.infof("Frontend rebuilt")
+			}
+		}
+		if backendChanged {
+			b.infof("Backend changed, rebuilding...")
+			if err := backend.rebuild(ctx, backendPath); err != nil {
+				b.infof("Backend build failed, keeping the previous version running: %v", err)
+			}
+		}
+	})
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("development server failed: %w", err)
+	default:
+		return nil
+	}
+}
+
+// Opens url in the default browser without waiting for it
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("cmd", "/c", "start", "", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+func absPath(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	return abs
+}
+
+// Serves the built frontend from disk with SPA fallback and proxies the API prefix to the backend
+func (b *builder) devHandler(staticDir string) http.Handler {
+	mux := http.NewServeMux()
+
+	if prefix := normalizeAPIPrefix(b.options.APIPrefix); prefix != "" {
+		backendURL := &url.URL{Scheme: "http", Host: fmt.Sprintf("127.0.0.1:%d", b.options.BackendPort)}
+		proxy := httputil.NewSingleHostReverseProxy(backendURL)
+		mux.Handle(prefix, proxy)
+		mux.Handle(prefix+"/", proxy)
+	}
+
+	fileServer := http.FileServer(http.Dir(staticDir))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		name := filepath.Join(staticDir, filepath.FromSlash(path.Clean("/"+r.URL.Path)))
+		if info, err := os.Stat(name); err == nil && (!info.IsDir() || r.URL.Path == "/") {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join(staticDir, "index.html"))
+	})
+
+	return mux
+}
+
+// Backend process run by the dev server, rebuilt into a fresh binary on each change
+type devBackend struct {
+	b     *builder
+	mu    sync.Mutex
+	dir   string
+	cmd   *exec.Cmd
+	done  chan struct{}
+	build int
+}
+
+// Builds the backend and, if that succeeds, replaces the running process.
+// Each build gets its own binary so a running one is never overwritten.
+func (d *devBackend) rebuild(ctx context.Context, backendPath string) error {
+	d.build++
+	binary := filepath.Join(d.dir, fmt.Sprintf("backend-binary-%d", d.build))
+	binary = addPlatformExtension(binary, runtime.GOOS)
+	if err := d.b.buildGoBackend(ctx, backendPath, binary); err != nil {
+		return err
+	}
+
+	d.stop()
+	return d.start(binary)
+}
+
+func (d *devBackend) start(binary string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cmd := exec.Command(binary)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("BACKEND_PORT=%d", d.b.options.BackendPort))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(done)
+	}()
+	d.cmd, d.done = cmd, done
+	d.b.infof("Backend started on port %d", d.b.options.BackendPort)
+	return nil
+}
+
+// Kills the running backend and its children, waiting for it to exit
+func (d *devBackend) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cmd == nil {
+		return
+	}
+	killProcessTree(d.cmd)
+	<-d.done
+	d.cmd = nil
+}
+
+// Recursively watches the backend and frontend trees and reports debounced changes
+type devWatcher struct {
+	b        *builder
+	watcher  *fsnotify.Watcher
+	backend  string
+	frontend string
+	output   string
+}
+
+// Watches root and every directory below it that isn't ignored
+func (w *devWatcher) add(root string) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != root && w.ignored(p) {
+			return filepath.SkipDir
+		}
+		return w.watcher.Add(p)
+	})
+}
+
+// Reports whether a path is inside an ignored directory or the build output
+func (w *devWatcher) ignored(p string) bool {
+	if within(w.output, p) {
+		return true
+	}
+	for _, part := range strings.Split(filepath.ToSlash(p), "/") {
+		if devIgnoredDirs[part] {
+			return true
+		}
+	}
+	return false
+}
+
+// Processes events until ctx is done or the watcher is closed, calling
+// rebuild once changes settle
+func (w *devWatcher) run(ctx context.Context, rebuild func(frontendChanged, backendChanged bool)) {
+	timer := time.NewTimer(devDebounce)
+	timer.Stop()
+	var frontendChanged, backendChanged bool
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if w.ignored(event.Name) {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					w.add(event.Name)
+				}
+			}
+
+			// The more specific root wins when one tree contains the other
+			inBackend, inFrontend := within(w.backend, event.Name), within(w.frontend, event.Name)
+			if inBackend && (!inFrontend || len(w.backend) >= len(w.frontend)) {
+				backendChanged = true
+			} else if inFrontend {
+				frontendChanged = true
+			}
+			timer.Reset(devDebounce)
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.b.infof("File watcher error: %v", err)
+
+		case <-ctx.Done():
+			return
+
+		case <-timer.C:
+			rebuild(frontendChanged, backendChanged)
+			frontendChanged, backendChanged = false, false
+		}
+	}
+}
+
+// Reports whether p is root or inside it
+func within(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
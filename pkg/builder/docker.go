@@ -0,0 +1,74 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Dockerfile written next to the bundle with --docker. The bundle is built
+// with CGO disabled, so it runs on a base image without libc.
+const dockerfileTemplate = `FROM {{.Base}}
+WORKDIR /app
+COPY [{{printf "%q" .Binary}}, "/app/"]
+{{- range .Backends}}
+COPY [{{printf "%q" .}}, "/app/"]
+{{- end}}
+ENV PORT={{.Port}}
+# Listen on all interfaces so the exposed port is reachable, whatever --host is
+ENV HOST=0.0.0.0
+EXPOSE {{.Port}}
+ENTRYPOINT [{{printf "%q" (print "/app/" .Binary)}}]
+`
+
+// Values substituted into dockerfileTemplate
+type dockerfileData struct {
+	Base     string
+	Binary   string
+	Backends []string // backend binaries shipped alongside the bundle
+	Port     int
+}
+
+// Writes a Dockerfile for the bundle into outputDir and, when --docker-tag is
+// set, builds the image with outputDir as the context
+func (b *builder) buildDockerImage(ctx context.Context, outputDir, binary string, backends []backendSpec) error {
+	data := dockerfileData{Base: b.options.DockerBase, Binary: binary, Port: defaultPort}
+	if b.options.ExternalBackend {
+		for _, backend := range backends {
+			if len(backend.Command) == 0 {
+				data.Backends = append(data.Backends, backend.Binary)
+			}
+		}
+	}
+
+	dockerfile := filepath.Join(outputDir, "Dockerfile")
+	if b.options.DryRun {
+		b.infof("[dry-run] would write %s", dockerfile)
+	} else {
+		var buf strings.Builder
+		if err := template.Must(template.New("Dockerfile").Parse(dockerfileTemplate)).Execute(&buf, data); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dockerfile, []byte(buf.String()), 0644); err != nil {
+			return err
+		}
+		b.infof("Dockerfile written to: %s", dockerfile)
+	}
+
+	if b.options.DockerTag == "" {
+		return nil
+	}
+	b.infof("Building Docker image %s...", b.options.DockerTag)
+	cmd := exec.CommandContext(ctx, "docker", "build", "-t", b.options.DockerTag, "-f", dockerfile, outputDir)
+	cmd.Stdout = b.commandStdout()
+	cmd.Stderr = os.Stderr
+	if err := b.runCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("docker build failed: %w", err)
+	}
+	b.logDone("Docker image built: %s", b.options.DockerTag)
+	return nil
+}
@@ -0,0 +1,70 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Parses a .env file into KEY=VALUE entries in file order. Blank lines,
+// # comments and an "export " prefix are allowed; values may be single
+// quoted (taken literally) or double quoted (with \n, \" and \\ escapes).
+func parseDotenv(data string) ([]string, error) {
+	var env []string
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", i+1)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value = strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1])
+		default:
+			// Unquoted values may end with a comment
+			if j := strings.Index(value, " #"); j >= 0 {
+				value = strings.TrimSpace(value[:j])
+			}
+		}
+		env = append(env, key+"="+value)
+	}
+	return env, nil
+}
+
+// Returns the environment for the frontend build: the inherited environment,
+// the --base-path, then --env-file entries, then --env entries, so later ones win
+func (b *builder) frontendBuildEnv() ([]string, error) {
+	env := os.Environ()
+	if prefix := normalizeAPIPrefix(b.options.BasePath); prefix != "" {
+		// next.config can pass this to basePath so asset URLs include the prefix
+		env = append(env, "NEXT_PUBLIC_BASE_PATH="+prefix)
+	}
+	if b.options.FrontendEnvFile != "" {
+		data, err := os.ReadFile(b.options.FrontendEnvFile)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := parseDotenv(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.options.FrontendEnvFile, err)
+		}
+		env = append(env, entries...)
+	}
+	for _, entry := range b.options.FrontendEnv {
+		if key, _, ok := strings.Cut(entry, "="); !ok || key == "" {
+			return nil, fmt.Errorf("invalid --env %q: expected KEY=VALUE", entry)
+		}
+		env = append(env, entry)
+	}
+	return env, nil
+}
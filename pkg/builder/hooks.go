@@ -0,0 +1,64 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Paths of the build in progress, exported to hooks as GONEXT_* variables
+type hookPaths struct {
+	Backend   string
+	Frontend  string
+	OutputDir string
+	Binary    string
+	TempDir   string
+}
+
+// Returns the environment for --pre-build-cmd and --post-build-cmd hooks: the
+// current one plus the build's paths and target
+func (b *builder) hookEnv(paths hookPaths) []string {
+	abs := func(p string) string {
+		if p == "" {
+			return ""
+		}
+		return absPath(p)
+	}
+	return append(os.Environ(),
+		"GONEXT_BACKEND_PATH="+abs(paths.Backend),
+		"GONEXT_FRONTEND_PATH="+abs(paths.Frontend),
+		"GONEXT_OUTPUT_DIR="+abs(paths.OutputDir),
+		"GONEXT_OUTPUT_BINARY="+abs(paths.Binary),
+		"GONEXT_TEMP_DIR="+paths.TempDir,
+		"GONEXT_GOOS="+goEnvOrHost(b.options.GOOS, runtime.GOOS),
+		"GONEXT_GOARCH="+goEnvOrHost(b.options.GOARCH, runtime.GOARCH),
+		"GONEXT_APP_VERSION="+b.options.AppVersion,
+	)
+}
+
+// Runs each hook command in order in the working directory, stopping at the
+// first one that fails. stage names the hooks in logs and errors.
+func (b *builder) runHooks(ctx context.Context, stage string, commands []string, env []string) error {
+	for _, command := range commands {
+		args, err := splitNonEmptyCommand(command)
+		if err != nil {
+			return fmt.Errorf("invalid %s hook %q: %w", stage, command, err)
+		}
+
+		b.infof("Running %s hook: %s", stage, strings.Join(args, " "))
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Env = env
+		stdout, stderr := b.stageOutput(stage)
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+		err = b.runCommand(ctx, cmd)
+		stdout.Flush()
+		stderr.Flush()
+		if err != nil {
+			return fmt.Errorf("%s hook %q failed: %w", stage, command, err)
+		}
+	}
+	return nil
+}
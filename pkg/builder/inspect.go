@@ -0,0 +1,133 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Precedes the bundle information stored in the generated binary, which
+// ends at the next NUL byte
+const bundleInfoMarker = "gonext-bundle-info:"
+
+// BundleInfo is the build information embedded into every bundle
+type BundleInfo struct {
+	Version   string       `json:"version"`
+	Commit    string       `json:"commit"`
+	BuildDate string       `json:"buildDate"`
+	GoNext    string       `json:"gonext"`
+	Backends  []string     `json:"backends,omitempty"`
+	Routes    BundleRoutes `json:"routes"`
+	Files     []BundleFile `json:"files"`
+}
+
+// BundleRoutes are the routes configured in the generated server; empty ones are disabled
+type BundleRoutes struct {
+	BasePath  string   `json:"basePath,omitempty"`
+	APIPrefix string   `json:"apiPrefix,omitempty"`
+	Health    string   `json:"health,omitempty"`
+	Metrics   string   `json:"metrics,omitempty"`
+	Manifest  string   `json:"manifest,omitempty"`
+	Frontends []string `json:"frontends"`
+}
+
+// BundleFile is an embedded frontend file and its stored size
+type BundleFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// Collects the bundle information for data, listing the frontend files
+// copied into tempDir
+func (b *builder) newBundleInfo(tempDir string, data mainTemplateData) (BundleInfo, error) {
+	info := BundleInfo{
+		Version:   b.options.AppVersion,
+		Commit:    gitCommit(b.sourceDir()),
+		BuildDate: data.BuildTime.UTC().Format(time.RFC3339),
+		GoNext:    b.options.GoNextVersion,
+		Routes: BundleRoutes{
+			BasePath: data.BasePath,
+			Health:   data.HealthzPath,
+			Manifest: data.ManifestPath,
+		},
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if data.Backend {
+		info.Routes.APIPrefix = data.APIPrefix
+		for _, backend := range data.Backends {
+			info.Backends = append(info.Backends, backend.Binary)
+		}
+	}
+	if data.Metrics {
+		info.Routes.Metrics = "/metrics"
+	}
+
+	for _, frontend := range data.Frontends {
+		info.Routes.Frontends = append(info.Routes.Frontends, frontend.Prefix+"/")
+		root := filepath.Join(tempDir, frontend.Dir)
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(tempDir, p)
+			if err != nil {
+				return err
+			}
+			info.Files = append(info.Files, BundleFile{Path: filepath.ToSlash(rel), Size: fi.Size()})
+			return nil
+		})
+		if err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+// Encodes info for the generated server's BundleInfo constant
+func encodeBundleInfo(info BundleInfo) (string, error) {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return "", err
+	}
+	// json.Marshal escapes NUL bytes, so the terminator can't occur inside
+	return bundleInfoMarker + string(data) + "\x00", nil
+}
+
+// ReadBundleInfo finds and decodes the bundle information stored in binary
+func ReadBundleInfo(binary string) (BundleInfo, error) {
+	var info BundleInfo
+	data, err := os.ReadFile(binary)
+	if err != nil {
+		return info, err
+	}
+	marker := []byte(bundleInfoMarker)
+	// An embedded file could contain the marker too, so keep looking until
+	// the bytes after it decode
+	for rest := data; ; {
+		i := bytes.Index(rest, marker)
+		if i < 0 {
+			return info, fmt.Errorf("no bundle information found; was it built by GoNext with the built-in template?")
+		}
+		rest = rest[i+len(marker):]
+		end := bytes.IndexByte(rest, 0)
+		if end < 0 {
+			end = len(rest)
+		}
+		if err := json.Unmarshal(rest[:end], &info); err == nil {
+			return info, nil
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Log levels, from most to least verbose
+const (
+	levelDebug = iota
+	levelInfo
+	levelError
+)
+
+// Logger writes leveled progress messages for the --verbose and --quiet
+// flags. It writes where the standard logger does, without changing its flags.
+type Logger struct {
+	level  int
+	logger *log.Logger
+}
+
+// NewLogger returns a Logger that also shows debug messages, with their file
+// and line, when verbose is set, and only errors when quiet is set
+func NewLogger(verbose, quiet bool) *Logger {
+	l := &Logger{level: levelInfo}
+	flags := log.LstdFlags
+	switch {
+	case verbose:
+		l.level = levelDebug
+		flags |= log.Lshortfile
+	case quiet:
+		l.level = levelError
+	}
+	l.logger = log.New(log.Writer(), "", flags)
+	return l
+}
+
+// Debugf logs details such as resolved paths and full commands, shown with --verbose
+func (l *Logger) Debugf(format string, v ...any) {
+	l.output(levelDebug, format, v...)
+}
+
+// Infof logs high-level progress, hidden with --quiet
+func (l *Logger) Infof(format string, v ...any) {
+	l.output(levelInfo, format, v...)
+}
+
+// Logs a message at level, attributed to the caller of the method calling output
+func (l *Logger) output(level int, format string, v ...any) {
+	if l.level <= level {
+		l.logger.Output(3, fmt.Sprintf(format, v...))
+	}
+}
+
+// Logs details such as resolved paths and full commands, shown with --verbose
+func (b *builder) debugf(format string, v ...any) {
+	b.logger.output(levelDebug, format, v...)
+}
+
+// Logs high-level progress, hidden with --quiet
+func (b *builder) infof(format string, v ...any) {
+	b.logger.output(levelInfo, format, v...)
+}
+
+// Returns where subprocess stdout goes; discarded with --quiet
+func (b *builder) commandStdout() io.Writer {
+	if b.logger.level >= levelError {
+		return io.Discard
+	}
+	return os.Stdout
+}
+
+// Writer that prefixes each line of subprocess output with its build stage,
+// so output from concurrent builds stays readable
+type prefixWriter struct {
+	w      io.Writer
+	prefix []byte
+	buf    []byte
+}
+
+// Returns stdout and stderr writers for a build stage's subprocess. Call
+// Flush on both once the subprocess has exited.
+func (b *builder) stageOutput(stage string) (stdout, stderr *prefixWriter) {
+	prefix := []byte("[" + stage + "] ")
+	return &prefixWriter{w: b.commandStdout(), prefix: prefix}, &prefixWriter{w: os.Stderr, prefix: prefix}
+}
+
+// Writes every complete line with the prefix, holding back a trailing partial line
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	var out []byte
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		out = append(out, p.prefix...)
+		out = append(out, p.buf[:i+1]...)
+		p.buf = p.buf[i+1:]
+	}
+	if len(out) > 0 {
+		if _, err := p.w.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// Writes any remaining partial line
+func (p *prefixWriter) Flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	line := append(append(p.prefix[:len(p.prefix):len(p.prefix)], p.buf...), '\n')
+	p.buf = nil
+	_, err := p.w.Write(line)
+	return err
+}
+
+// Number of captured stderr lines included in a failed build's error
+const stderrSummaryLines = 20
+
+// Lines that look like the actual cause of a failed build
+var stderrErrorPattern = regexp.MustCompile(`(?i)\berror\b|\bfailed\b|ERR!`)
+
+// Captures a subprocess's stderr, keeping the lines that look like errors and
+// the last few lines, so a failure can be reported without the build noise
+type stderrCapture struct {
+	partial []byte
+	errors  []string
+	tail    []string
+}
+
+func (c *stderrCapture) Write(data []byte) (int, error) {
+	c.partial = append(c.partial, data...)
+	for {
+		i := bytes.IndexByte(c.partial, '\n')
+		if i < 0 {
+			break
+		}
+		c.addLine(string(c.partial[:i]))
+		c.partial = c.partial[i+1:]
+	}
+	return len(data), nil
+}
+
+func (c *stderrCapture) addLine(line string) {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if stderrErrorPattern.MatchString(line) && len(c.errors) < stderrSummaryLines {
+		c.errors = append(c.errors, line)
+	}
+	c.tail = append(c.tail, line)
+	if len(c.tail) > stderrSummaryLines {
+		c.tail = c.tail[1:]
+	}
+}
+
+// Returns the error lines, or the last lines when none matched, indented for
+// an error message; "" when nothing was captured
+func (c *stderrCapture) Summary() string {
+	if len(c.partial) > 0 {
+		c.addLine(string(c.partial))
+		c.partial = nil
+	}
+	lines := c.errors
+	if len(lines) == 0 {
+		lines = c.tail
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n  " + strings.Join(lines, "\n  ")
+}
+
+// FormatBytes formats a byte count for log messages, e.g. 1.5 MiB
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
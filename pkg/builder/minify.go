@@ -0,0 +1,129 @@
+package builder
+
+import (
+	"bytes"
+	"os"
+)
+
+// Elements whose content is copied verbatim by minifyHTML
+var rawTextElements = []string{"pre", "textarea", "script", "style"}
+
+// Conservatively minifies HTML: comments are dropped (except conditional
+// comments) and runs of whitespace collapse to a single space, leaving
+// quoted attribute values and the content of raw text elements untouched
+func minifyHTML(src []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(src))
+
+	inTag := false
+	var quote byte
+	space := false // a collapsed whitespace run is pending
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+
+		if quote != 0 {
+			out.WriteByte(c)
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		if isHTMLSpace(c) {
+			space = true
+			continue
+		}
+		if space {
+			// Whitespace right before the end of a tag carries no meaning
+			if !inTag || c != '>' {
+				out.WriteByte(' ')
+			}
+			space = false
+		}
+
+		switch {
+		case inTag:
+			out.WriteByte(c)
+			if c == '"' || c == '\'' {
+				quote = c
+			} else if c == '>' {
+				inTag = false
+			}
+		case c == '<' && bytes.HasPrefix(src[i:], []byte("<!--")):
+			end := bytes.Index(src[i+4:], []byte("-->"))
+			if end < 0 {
+				end = len(src) - i - 4
+			} else {
+				end += 3
+			}
+			if bytes.HasPrefix(src[i:], []byte("<!--[if")) {
+				out.Write(src[i : i+4+end])
+			}
+			i += 3 + end
+		case c == '<':
+			if name, end := rawTextElement(src, i); name != "" {
+				out.Write(src[i:end])
+				i = end - 1
+				continue
+			}
+			out.WriteByte(c)
+			inTag = true
+		default:
+			out.WriteByte(c)
+		}
+	}
+	if space {
+		out.WriteByte(' ')
+	}
+	return out.Bytes()
+}
+
+// Reports whether src[i:] opens a raw text element, returning its name and
+// the offset just past its closing tag
+func rawTextElement(src []byte, i int) (string, int) {
+	for _, name := range rawTextElements {
+		open := "<" + name
+		if len(src) < i+len(open)+1 || !bytes.EqualFold(src[i:i+len(open)], []byte(open)) {
+			continue
+		}
+		if next := src[i+len(open)]; next != '>' && !isHTMLSpace(next) {
+			continue
+		}
+		closing := []byte("</" + name)
+		end := bytes.Index(bytes.ToLower(src[i:]), closing)
+		if end < 0 {
+			return name, len(src)
+		}
+		end += i
+		if gt := bytes.IndexByte(src[end:], '>'); gt >= 0 {
+			return name, end + gt + 1
+		}
+		return name, len(src)
+	}
+	return "", 0
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// Writes the minified copy of the HTML file src to dst, preserving the file
+// mode and modification time
+func minifyHTMLFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, minifyHTML(data), info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
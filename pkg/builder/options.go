@@ -0,0 +1,144 @@
+package builder
+
+import "time"
+
+// BuildOptions configures a build. Each field corresponds to the GoNext flag
+// of the same name; start from DefaultOptions to get the flags' defaults.
+type BuildOptions struct {
+	// Positional arguments. BackendPath is unused with NoBackend,
+	// BackendBinary or BackendCommand, and FrontendPath with Frontends.
+	BackendPath  string
+	FrontendPath string
+	OutputDir    string
+	BinaryName   string
+
+	// Frontend build
+	Framework        string   // nextjs, vite, cra or sveltekit; detected when empty
+	PackageManager   string   // npm, pnpm or yarn; detected from lockfiles when empty
+	OutDir           string   // build output directory relative to the frontend path
+	NoInstall        bool     // don't install dependencies when node_modules is missing
+	InstallCommand   string   // command installing dependencies instead of the package manager's, split like a shell
+	InstallDir       string   // directory dependencies are installed in, e.g. a monorepo root; FrontendPath when empty
+	FrontendEnv      []string // KEY=VALUE variables for the frontend build
+	FrontendEnvFile  string   // .env file of variables for the frontend build
+	BuildScript      string   // package.json script that builds the frontend
+	BuildArgs        string   // extra arguments for the build script, split like a shell
+	BuildRetries     int      // retries of a failed frontend build
+	PrebuiltFrontend bool     // bundle the existing build output without building
+	CacheDir         string   // frontend build cache; the OS cache directory when empty
+	NoCache          bool     // always rebuild the frontend
+	Frontends        []string // name=path[:prefix] mounts replacing FrontendPath
+	EmbedDir         string   // directory name the frontend is embedded under
+
+	// Frontend assets
+	CompressAssets  bool
+	StripSourceMaps bool
+	MinifyHTML      bool
+	Exclude         []string // glob patterns of frontend files left out of the bundle
+
+	// Backends
+	NoBackend         bool
+	ExternalBackend   bool   // ship the backend next to the bundle instead of embedding it
+	BackendBinary     string // prebuilt backend executable
+	BackendCommand    string // command run as the backend instead of a Go binary
+	SkipBackendBuild  bool
+	BackendBinaryName string
+	Backends          []string // name or name:port directories below BackendPath
+	BackendPort       int
+	BackendLDFlags    string
+	BackendTags       string
+
+	// Go build of the bundle
+	LDFlags      string
+	Tags         string
+	TrimPath     bool
+	Strip        bool
+	GOOS         string // target platform; the host's when empty
+	GOARCH       string
+	GoVersion    string // go directive of the generated module
+	ModulePath   string
+	Reproducible bool
+	Timeout      time.Duration // limit for the whole build, 0 for none
+	AppVersion   string
+	TemplatePath string // text/template file replacing the built-in main.go template
+
+	// Generated server
+	APIPrefix           string
+	BackendReadyTimeout time.Duration
+	BackendHealthPath   string
+	NoRestart           bool
+	BackendGracePeriod  time.Duration
+	ReadHeaderTimeout   time.Duration
+	ReadTimeout         time.Duration
+	WriteTimeout        time.Duration
+	IdleTimeout         time.Duration
+	ShutdownTimeout     time.Duration
+	Compression         bool
+	ImmutablePattern    string
+	TLSCert             string
+	TLSKey              string
+	Host                string
+	CORSOrigins         []string
+	CORSMethods         []string
+	CORSHeaders         []string
+	SPAFallback         bool
+	StrictRoutes        bool // only fall back to SPAEntry for requests accepting text/html
+	SPAEntry            string
+	TrailingSlash       bool
+	AccessLog           bool
+	HealthzPath         string
+	HealthzCacheTTL     time.Duration
+	Metrics             bool
+	BasePath            string
+	Manifest            bool
+	ManifestPath        string
+	Dotenv              bool
+
+	// Outputs next to the bundle
+	Systemd    bool
+	Docker     bool
+	DockerTag  string
+	DockerBase string
+
+	// Behaviour of the build itself
+	DryRun            bool
+	KeepTemp          bool
+	Verbose           bool
+	Quiet             bool
+	GoNextVersion     string   // version of the GoNext build stamped into bundles
+	PreBuildCommands  []string // hooks run before the frontend and backend builds; a failure fails the build
+	PostBuildCommands []string // hooks run once the bundle and its outputs are written
+}
+
+// DefaultOptions returns the options GoNext uses when no flags are set
+func DefaultOptions() BuildOptions {
+	return BuildOptions{
+		BackendBinaryName: "backend-binary",
+		BackendPort:       5000,
+
+		TrimPath:   true,
+		Strip:      true,
+		ModulePath: "gonext",
+
+		APIPrefix:           "/api",
+		BackendReadyTimeout: 10 * time.Second,
+		BackendGracePeriod:  5 * time.Second,
+		ReadHeaderTimeout:   10 * time.Second,
+		ReadTimeout:         time.Minute,
+		WriteTimeout:        time.Minute,
+		IdleTimeout:         2 * time.Minute,
+		ShutdownTimeout:     5 * time.Second,
+		ImmutablePattern:    "^/_next/static/",
+		CORSMethods:         []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		CORSHeaders:         []string{"Content-Type", "Authorization"},
+		SPAFallback:         true,
+		SPAEntry:            "index.html",
+		HealthzPath:         "/healthz",
+		HealthzCacheTTL:     time.Second,
+		ManifestPath:        "/_gonext/manifest.json",
+
+		DockerBase: "gcr.io/distroless/static-debian12:nonroot",
+
+		GoNextVersion: "dev",
+	}
+}
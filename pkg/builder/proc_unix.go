@@ -0,0 +1,18 @@
+//go:build !windows
+
+package builder
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Starts the command in its own process group so its children can be killed with it
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// Kills the command and every process in its group
+func killProcessTree(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package builder
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// Process groups are not needed on Windows, where taskkill walks the tree
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// Kills the command and all of its child processes
+func killProcessTree(cmd *exec.Cmd) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
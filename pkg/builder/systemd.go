@@ -0,0 +1,86 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// systemd unit written next to the bundle with --systemd
+const systemdUnitTemplate = `[Unit]
+Description={{.Name}} (GoNext bundle)
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+ExecStart={{.ExecStart}}
+WorkingDirectory={{.WorkingDirectory}}
+Environment={{env "PORT" .Port}}
+{{- if .Host}}
+Environment={{env "HOST" .Host}}
+{{- end}}
+Restart=on-failure
+RestartSec=2
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// Values substituted into systemdUnitTemplate
+type systemdUnitData struct {
+	Name             string
+	ExecStart        string
+	WorkingDirectory string
+	Port             int
+	Host             string
+}
+
+// Writes <binary>.service into the bundle's directory, starting the bundle
+// from its absolute path
+func (b *builder) writeSystemdUnit(outputBinary string) error {
+	execStart, err := filepath.Abs(outputBinary)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(outputBinary)
+	data := systemdUnitData{
+		Name:             name,
+		ExecStart:        systemdQuote(execStart),
+		WorkingDirectory: filepath.Dir(execStart),
+		Port:             defaultPort,
+		Host:             b.options.Host,
+	}
+
+	unit := filepath.Join(filepath.Dir(outputBinary), name+".service")
+	if b.options.DryRun {
+		b.infof("[dry-run] would write %s", unit)
+		return nil
+	}
+	var buf strings.Builder
+	if err := template.Must(template.New("service").Funcs(template.FuncMap{"env": systemdEnv}).Parse(systemdUnitTemplate)).Execute(&buf, data); err != nil {
+		return err
+	}
+	if err := os.WriteFile(unit, []byte(buf.String()), 0644); err != nil {
+		return err
+	}
+	b.infof("systemd unit written to: %s", unit)
+	return nil
+}
+
+// Formats a quoted Environment= assignment, escaping quotes, backslashes and
+// the % that starts a systemd specifier
+func systemdEnv(name string, value any) string {
+	assignment := fmt.Sprintf("%s=%v", name, value)
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%").Replace(assignment) + `"`
+}
+
+// Quotes a path for ExecStart when it contains spaces
+func systemdQuote(path string) string {
+	if !strings.ContainsAny(path, " \t\"\\") {
+		return path
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(path) + `"`
+}
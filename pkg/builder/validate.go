@@ -0,0 +1,243 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/scanner"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Verifies frontendPath contains a package.json defining the build script
+func checkFrontend(frontendPath, script string) error {
+	if info, err := os.Stat(frontendPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("frontend directory %s does not exist", frontendPath)
+	}
+
+	packageJSON := filepath.Join(frontendPath, "package.json")
+	data, err := os.ReadFile(packageJSON)
+	if err != nil {
+		return fmt.Errorf("%s not found; is %s a frontend project?", packageJSON, frontendPath)
+	}
+
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", packageJSON, err)
+	}
+	if _, ok := pkg.Scripts[script]; !ok {
+		return fmt.Errorf("%s has no %q script", packageJSON, script)
+	}
+	return nil
+}
+
+// Reports whether the package.json in dir declares any dependencies, or dir
+// is a workspace root whose members may
+func hasDependencies(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, "pnpm-workspace.yaml")); err == nil {
+		return true
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return false
+	}
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+		Workspaces      json.RawMessage   `json:"workspaces"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return false
+	}
+	return len(pkg.Dependencies)+len(pkg.DevDependencies) > 0 || len(pkg.Workspaces) > 0
+}
+
+// Signs of each framework in a frontend project: a dependency, config files
+// and a build command in the package.json scripts
+var frameworkSignatures = []struct {
+	Framework   string
+	Dependency  string
+	ConfigFiles []string
+	Command     string
+}{
+	{Framework: "nextjs", Dependency: "next", ConfigFiles: []string{"next.config.js", "next.config.mjs", "next.config.ts"}, Command: "next build"},
+	{Framework: "sveltekit", Dependency: "@sveltejs/kit", ConfigFiles: []string{"svelte.config.js", "svelte.config.mjs", "svelte.config.ts"}, Command: "svelte-kit"},
+	{Framework: "cra", Dependency: "react-scripts", Command: "react-scripts build"},
+	{Framework: "vite", Dependency: "vite", ConfigFiles: []string{"vite.config.js", "vite.config.mjs", "vite.config.ts"}, Command: "vite build"},
+}
+
+// Infers the framework of the project in frontendPath from its package.json
+// dependencies and scripts and its config files. It returns "" when nothing
+// matches and fails when several frameworks do.
+func detectFramework(frontendPath string) (string, error) {
+	var pkg struct {
+		Scripts         map[string]string `json:"scripts"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if data, err := os.ReadFile(filepath.Join(frontendPath, "package.json")); err == nil {
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			return "", fmt.Errorf("failed to parse package.json: %w", err)
+		}
+	}
+
+	var found []string
+	for _, sig := range frameworkSignatures {
+		_, dep := pkg.Dependencies[sig.Dependency]
+		_, devDep := pkg.DevDependencies[sig.Dependency]
+		match := dep || devDep
+		for _, name := range sig.ConfigFiles {
+			if _, err := os.Stat(filepath.Join(frontendPath, name)); err == nil {
+				match = true
+			}
+		}
+		for _, script := range pkg.Scripts {
+			if strings.Contains(script, sig.Command) {
+				match = true
+			}
+		}
+		if match {
+			found = append(found, sig.Framework)
+		}
+	}
+	// SvelteKit builds with Vite
+	if slices.Contains(found, "sveltekit") {
+		found = slices.DeleteFunc(found, func(name string) bool { return name == "vite" })
+	}
+
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("%s looks like %s; set --frontend-framework", frontendPath, strings.Join(found, " and "))
+	}
+}
+
+// Verifies the copied frontend in dir has an index.html or the SPA entry
+// page, possibly gzipped, which the generated server needs for / and its
+// SPA fallback
+func checkIndexFile(dir, entry string) error {
+	for _, name := range []string{"index.html", entry} {
+		for _, file := range []string{name, name + ".gz"} {
+			if info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(file))); err == nil && !info.IsDir() {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("neither index.html nor %s found in %s", entry, dir)
+}
+
+// Verifies backendPath contains a go.mod or at least one .go file
+func checkBackend(backendPath string) error {
+	entries, err := os.ReadDir(backendPath)
+	if err != nil {
+		return fmt.Errorf("backend directory %s does not exist", backendPath)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if entry.Name() == "go.mod" || strings.HasSuffix(entry.Name(), ".go") {
+			return nil
+		}
+	}
+	return fmt.Errorf("no go.mod or .go files found in backend directory %s", backendPath)
+}
+
+// Verifies path is an executable file for the target platform
+func checkExecutable(path, goos string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%s does not exist", path)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a file", path)
+	}
+	// Windows has no executable bit
+	if goos != "windows" && info.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("%s is not executable", path)
+	}
+	return nil
+}
+
+// Checks the generated main.go in dir before the final build, so template
+// substitution problems (such as a frontend directory name that breaks the
+// embed directive) are reported clearly instead of as a build failure
+func (b *builder) validateMain(ctx context.Context, dir string) error {
+	b.infof("Validating generated main.go...")
+	if !b.options.DryRun {
+		src, err := os.ReadFile(filepath.Join(dir, "main.go"))
+		if err != nil {
+			return err
+		}
+		if _, err := format.Source(src); err != nil {
+			return fmt.Errorf("generated main.go is not valid Go: %w%s", err, offendingLine(src, err))
+		}
+	}
+
+	args := []string{"vet"}
+	if b.options.Tags != "" {
+		args = append(args, "-tags", b.options.Tags)
+	}
+	cmd := exec.CommandContext(ctx, "go", append(args, ".")...)
+	cmd.Dir = dir
+	cmd.Env = b.goBuildEnv()
+	cmd.Stdout = b.commandStdout()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := b.runCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("go vet rejected the generated main.go: %w\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Formats the generated source line a syntax error points at, or "" if unknown
+func offendingLine(src []byte, err error) string {
+	var list scanner.ErrorList
+	if !errors.As(err, &list) || len(list) == 0 {
+		return ""
+	}
+	lines := bytes.Split(src, []byte("\n"))
+	if n := list[0].Pos.Line; n > 0 && n <= len(lines) {
+		return fmt.Sprintf("\n  line %d: %s", n, bytes.TrimSpace(lines[n-1]))
+	}
+	return ""
+}
+
+// Verifies name can be embedded as a directory with a quoted //go:embed
+// pattern. Go only accepts letters, digits, spaces and some punctuation in
+// embedded file names, and [ ] would be read as a glob pattern.
+func checkEmbedName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("frontend directory name %q can't be embedded: it must be a single path element", name)
+	}
+	if strings.HasSuffix(name, ".") {
+		return fmt.Errorf("frontend directory name %q can't be embedded: it must not end with a dot", name)
+	}
+	for _, r := range name {
+		if !embedNameRuneOK(r) {
+			return fmt.Errorf("frontend directory name %q can't be embedded: character %q is not allowed; rename the directory", name, r)
+		}
+	}
+	return nil
+}
+
+func embedNameRuneOK(r rune) bool {
+	if r <= unicode.MaxASCII {
+		const allowed = "!#$%&()+,-.=@^_{}~ "
+		return '0' <= r && r <= '9' || 'A' <= r && r <= 'Z' || 'a' <= r && r <= 'z' || strings.ContainsRune(allowed, r)
+	}
+	return unicode.IsLetter(r)
+}
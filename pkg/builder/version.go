@@ -0,0 +1,95 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returns the short commit hash of the git checkout containing dir (the
+// working directory when empty), or "unknown" outside a git repository
+func gitCommit(dir string) string {
+	rev := exec.Command("git", "rev-parse", "--short", "HEAD")
+	rev.Dir = dir
+	out, err := rev.Output()
+	if err != nil {
+		return "unknown"
+	}
+	commit := strings.TrimSpace(string(out))
+	status := exec.Command("git", "status", "--porcelain")
+	status.Dir = dir
+	if out, err := status.Output(); err == nil && len(out) > 0 {
+		commit += "-dirty"
+	}
+	return commit
+}
+
+// Returns the directory whose git commit is stamped into the bundle: the
+// backend source, else the frontend, else the working directory
+func (b *builder) sourceDir() string {
+	for _, dir := range []string{b.options.BackendPath, b.options.FrontendPath} {
+		if info, err := os.Stat(dir); dir != "" && err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return ""
+}
+
+// Returns the time recorded as the bundle's build date and the mod-time of
+// its embedded files: SOURCE_DATE_EPOCH when set, the Unix epoch with
+// --reproducible, or else the current time.
+//
+// A reproducible bundle still depends on the Go toolchain version, the target
+// platform, the GoNext version, the git commit and --app-version stamped into
+// it, the frontend build output, and the module versions go mod tidy resolves.
+func (b *builder) buildTimestamp() time.Time {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		if seconds, err := strconv.ParseInt(epoch, 10, 64); err == nil {
+			return time.Unix(seconds, 0).UTC()
+		}
+	}
+	if b.options.Reproducible {
+		return time.Unix(0, 0).UTC()
+	}
+	return time.Now()
+}
+
+// Verifies SOURCE_DATE_EPOCH, when set, is a Unix timestamp
+func checkSourceDateEpoch() error {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return nil
+	}
+	if _, err := strconv.ParseInt(epoch, 10, 64); err != nil {
+		return fmt.Errorf("SOURCE_DATE_EPOCH %q is not a Unix timestamp", epoch)
+	}
+	return nil
+}
+
+// Returns the -ldflags -X settings that stamp build metadata into the
+// generated main package
+func (b *builder) metadataLDFlags(buildTime time.Time) string {
+	values := []struct{ name, value string }{
+		{"appVersion", b.options.AppVersion},
+		{"gitCommit", gitCommit(b.sourceDir())},
+		{"buildDate", buildTime.UTC().Format(time.RFC3339)},
+		{"gonextVersion", b.options.GoNextVersion},
+	}
+
+	var flags []string
+	for _, v := range values {
+		if v.value == "" {
+			continue
+		}
+		// go build splits -ldflags on spaces outside quotes
+		quote := "'"
+		if strings.Contains(v.value, quote) {
+			quote = `"`
+		}
+		flags = append(flags, fmt.Sprintf("-X %smain.%s=%s%s", quote, v.name, v.value, quote))
+	}
+	return strings.Join(flags, " ")
+}
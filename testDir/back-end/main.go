@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"os"
 )
 
 func helloHandler(w http.ResponseWriter, r *http.Request) {
@@ -16,8 +17,14 @@ func main() {
 
 	http.HandleFunc("/", helloHandler)
 
-	fmt.Println("Server starting on port 5000...")
-	err := http.ListenAndServe(":5000", nil)
+	// GoNext passes the backend port in BACKEND_PORT
+	port := os.Getenv("BACKEND_PORT")
+	if port == "" {
+		port = "5000"
+	}
+
+	fmt.Printf("Server starting on port %s...\n", port)
+	err := http.ListenAndServe(":"+port, nil)
 	if err != nil {
 		fmt.Println("Error starting server: ", err)
 	}